@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+// resolveContainerPaths returns the containers of the project_path lines, and whether a single path resolves to another container.
+func resolveContainerPaths(projectPath string, switchToPods bool) ([]string, bool, error) {
+	var entries []string
+	for _, line := range strings.Split(projectPath, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	if len(entries) == 0 {
+		// The working directory is searched.
+		entries = []string{""}
+	}
+
+	var containerPaths []string
+	var corrected bool
+	for _, entry := range entries {
+		if !isGlobPattern(entry) {
+			containerPath, entryCorrected, err := resolveContainerEntry(entry, switchToPods)
+			if err != nil {
+				return nil, false, err
+			}
+			containerPaths = appendUniquePath(containerPaths, containerPath)
+			corrected = corrected || entryCorrected
+			continue
+		}
+
+		matches, err := filepath.Glob(entry)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid project_path pattern (%s): %w", entry, err)
+		}
+		var matched int
+		for _, match := range matches {
+			if info, err := os.Stat(match); err != nil || !info.IsDir() {
+				continue
+			}
+			containerPath, _, err := resolveContainerEntry(match, switchToPods)
+			if errors.Is(err, errNoContainerFound) {
+				log.Printf("Skipping %s, no Xcode project or workspace in it", match)
+				continue
+			}
+			if err != nil {
+				return nil, false, err
+			}
+			containerPaths = appendUniquePath(containerPaths, containerPath)
+			matched++
+		}
+		if matched == 0 {
+			return nil, false, fmt.Errorf("project_path pattern (%s) matches no Xcode project or workspace", entry)
+		}
+		corrected = true
+	}
+
+	return containerPaths, corrected && len(containerPaths) == 1, nil
+}
+
+// resolveContainerEntry returns the container of a single path, and whether it differs from the path.
+func resolveContainerEntry(projectPath string, switchToPods bool) (string, bool, error) {
+	containerPath, corrected, err := resolveContainerPath(projectPath)
+	if err != nil {
+		return "", false, err
+	}
+	if projectPath, ok := schemelist.EmbeddedWorkspaceProject(containerPath); ok {
+		log.Printf("%s is the workspace embedded in %s, using the project", containerPath, filepath.Base(projectPath))
+		containerPath, corrected = projectPath, true
+	}
+	if workspacePath, ok, err := checkPodsWorkspace(containerPath, switchToPods); err != nil {
+		return "", false, err
+	} else if ok {
+		containerPath, corrected = workspacePath, true
+	}
+	return containerPath, corrected, nil
+}
+
+// containersDir returns the common parent directory of the containers.
+func containersDir(containerPaths []string) string {
+	dir := filepath.Dir(containerPaths[0])
+	for _, pth := range containerPaths[1:] {
+		dir = commonParentDir(dir, filepath.Dir(pth))
+	}
+	return dir
+}
+
+func isGlobPattern(pth string) bool {
+	return strings.ContainsAny(pth, "*?[")
+}
+
+func appendUniquePath(paths []string, pth string) []string {
+	for _, p := range paths {
+		if p == pth {
+			return paths
+		}
+	}
+	return append(paths, pth)
+}
+
+// openConfiguredContainer opens the container the step processes.
+func openConfiguredContainer(cfg Config) (schemelist.Container, error) {
+	return schemelist.Open(cfg.ContainerPath, cfg.Projects, cfg.ExcludedProjects)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveContainerPaths(t *testing.T) {
+	dir := t.TempDir()
+	makeTestDirs(t,
+		filepath.Join(dir, "apps", "a", "ios", "A.xcodeproj", "project.xcworkspace"),
+		filepath.Join(dir, "apps", "b", "ios", "B.xcodeproj"),
+		// Matched by the pattern, skipped as it has no container.
+		filepath.Join(dir, "apps", "docs", "ios"),
+		filepath.Join(dir, "pods", "App.xcodeproj"),
+	)
+	writeTestWorkspace(t, filepath.Join(dir, "pods", "App.xcworkspace"), "group:App.xcodeproj", "group:Pods/Pods.xcodeproj")
+	if err := os.WriteFile(filepath.Join(dir, "pods", "Podfile"), []byte("target 'App'\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	projectA := filepath.Join(dir, "apps", "a", "ios", "A.xcodeproj")
+	projectB := filepath.Join(dir, "apps", "b", "ios", "B.xcodeproj")
+	podsProject := filepath.Join(dir, "pods", "App.xcodeproj")
+	podsWorkspace := filepath.Join(dir, "pods", "App.xcworkspace")
+
+	tests := []struct {
+		name          string
+		projectPath   string
+		switchToPods  bool
+		want          []string
+		wantCorrected bool
+		wantErr       string
+	}{
+		{
+			name:        "listed paths, blank lines and duplicates dropped",
+			projectPath: projectA + "\n\n  " + projectB + "  \n" + projectA + "\n",
+			want:        []string{projectA, projectB},
+		},
+		{
+			name:        "pattern matching directories",
+			projectPath: filepath.Join(dir, "apps", "*", "ios"),
+			want:        []string{projectA, projectB},
+		},
+		{
+			name:          "pattern matching one container",
+			projectPath:   filepath.Join(dir, "apps", "a", "*"),
+			want:          []string{projectA},
+			wantCorrected: true,
+		},
+		{
+			name:          "searched directory",
+			projectPath:   filepath.Join(dir, "apps", "b"),
+			want:          []string{projectB},
+			wantCorrected: true,
+		},
+		{
+			name:          "embedded workspace",
+			projectPath:   filepath.Join(projectA, "project.xcworkspace"),
+			want:          []string{projectA},
+			wantCorrected: true,
+		},
+		{
+			name:        "pods project kept",
+			projectPath: podsProject,
+			want:        []string{podsProject},
+		},
+		{
+			name:          "pods workspace switched to",
+			projectPath:   podsProject,
+			switchToPods:  true,
+			want:          []string{podsWorkspace},
+			wantCorrected: true,
+		},
+		{
+			name:        "pattern matching no container",
+			projectPath: filepath.Join(dir, "apps", "docs", "*"),
+			wantErr:     "matches no Xcode project or workspace",
+		},
+		{
+			name:        "missing path",
+			projectPath: filepath.Join(dir, "Missing.xcodeproj"),
+			wantErr:     "does not exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, corrected, err := resolveContainerPaths(tt.projectPath, tt.switchToPods)
+			checkError(t, err, tt.wantErr)
+			if !reflect.DeepEqual(got, tt.want) || corrected != tt.wantCorrected {
+				t.Errorf("resolveContainerPaths() = %v, %v, want %v, %v", got, corrected, tt.want, tt.wantCorrected)
+			}
+		})
+	}
+}
+
+func TestContainersDir(t *testing.T) {
+	tests := []struct {
+		containerPaths []string
+		want           string
+	}{
+		{[]string{"/repo/ios/App.xcworkspace"}, "/repo/ios"},
+		{[]string{"/repo/apps/a/A.xcworkspace", "/repo/apps/b/B.xcworkspace"}, "/repo/apps"},
+		{[]string{"/repo/apps/a/A.xcworkspace", "/repo/shared/Core.xcodeproj", "/repo/apps/b/B.xcworkspace"}, "/repo"},
+		{[]string{"/repo/App.xcodeproj", "/other/Lib.xcodeproj"}, "/"},
+	}
+	for _, tt := range tests {
+		if got := containersDir(tt.containerPaths); got != tt.want {
+			t.Errorf("containersDir(%v) = %s, want %s", tt.containerPaths, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+)
+
+// printGenerationPlan prints the targets, the reasons for the skipped ones and the schemes the step would write, without writing.
+func printGenerationPlan(cfg Config, projects []xcodeproject.XcodeProj, projectToSchemes map[string][]generatedScheme, unlistedReason string) error {
+	fmt.Println()
+	log.Infof("Dry run, no Scheme is written")
+
+	for _, project := range projects {
+		schemes := projectToSchemes[project.Path]
+		targetToSchemes := map[string][]generatedScheme{}
+		for _, scheme := range schemes {
+			for _, entry := range scheme.BuildAction.BuildActionEntries {
+				targetToSchemes[entry.BuildableReference.BlueprintIdentifier] = append(targetToSchemes[entry.BuildableReference.BlueprintIdentifier], scheme)
+			}
+		}
+
+		fmt.Println()
+		log.Printf("%s:", pathRelativeToWorkspace(project.Path, cfg.ContainerPath))
+		for _, target := range project.Proj.Targets {
+			if targetSchemes, ok := targetToSchemes[target.ID]; ok {
+				for _, scheme := range targetSchemes {
+					log.Donef("- %s: Scheme %s%s", target.Name, scheme.Name, testTargetsDescription(scheme))
+				}
+				continue
+			}
+
+			log.Printf("- %s: skipped, %s", target.Name, skipReason(cfg, project, target, unlistedReason))
+		}
+	}
+
+	for _, project := range projects {
+		for _, scheme := range projectToSchemes[project.Path] {
+			contents, err := scheme.Marshal()
+			if err != nil {
+				return fmt.Errorf("marshalling scheme %s failed: %w", scheme.Name, err)
+			}
+
+			fmt.Println()
+			log.Infof("%s:", filepath.Join(pathRelativeToWorkspace(project.Path, cfg.ContainerPath), "xcshareddata", "xcschemes", scheme.Name+".xcscheme"))
+			fmt.Print(string(contents))
+		}
+	}
+
+	return nil
+}
+
+func testTargetsDescription(scheme generatedScheme) string {
+	var testTargets []string
+	for _, testable := range scheme.TestAction.Testables {
+		testTargets = append(testTargets, testable.BuildableReference.BlueprintName)
+	}
+
+	if len(testTargets) == 0 {
+		return ", no test targets attached"
+	}
+	return fmt.Sprintf(", test targets attached: %s", strings.Join(testTargets, ", "))
+}
+
+func skipReason(cfg Config, project xcodeproject.XcodeProj, target xcodeproject.Target, unlistedReason string) string {
+	switch {
+	case target.Type == xcodeproject.AggregateTargetType:
+		return "aggregate target"
+	case target.Type == xcodeproject.LegacyTargetType:
+		return "legacy (external build system) target"
+	case target.IsTest():
+		return "test target"
+	}
+
+	if cfg.SchemeSpec == nil {
+		if reason := cfg.TargetFilter.filter(target, project.Path); reason != "" {
+			return "filtered by " + reason
+		}
+	}
+
+	return unlistedReason
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+)
+
+const shellScriptActionType = "Xcode.IDEStandardExecutionActionsCore.ExecutionActionType.ShellScriptAction"
+
+// executionAction is a scheme pre- or post-action (Run Script).
+type executionAction struct {
+	ActionType    string                   `xml:"ActionType,attr"`
+	ActionContent shellScriptActionContent `xml:"ActionContent"`
+}
+
+type shellScriptActionContent struct {
+	Title                string                `xml:"title,attr"`
+	ScriptText           string                `xml:"scriptText,attr"`
+	ShellToRunWith       string                `xml:"shellToRunWith,attr,omitempty"`
+	EnvironmentBuildable *environmentBuildable `xml:"EnvironmentBuildable,omitempty"`
+}
+
+// environmentBuildable is the 'Provide build settings from' target of an execution action.
+type environmentBuildable struct {
+	BuildableReference xcscheme.BuildableReference
+}
+
+// schemeExecutionActions are the pre- and post-actions of a scheme action.
+type schemeExecutionActions struct {
+	Pre  []executionAction
+	Post []executionAction
+}
+
+// schemeActionName is a scheme action which supports execution actions.
+type schemeActionName string
+
+const (
+	buildSchemeAction   schemeActionName = "build"
+	testSchemeAction    schemeActionName = "test"
+	archiveSchemeAction schemeActionName = "archive"
+)
+
+// executionPhase tells if an execution action runs before or after the scheme action.
+type executionPhase string
+
+const (
+	preExecutionPhase  executionPhase = "pre"
+	postExecutionPhase executionPhase = "post"
+)
+
+// executionScript is a shell script configured to be added to the generated schemes.
+type executionScript struct {
+	Action     schemeActionName
+	Phase      executionPhase
+	ScriptPath string
+	ScriptText string
+}
+
+// parseExecutionScripts parses the '<action>_<phase>: <script path>' lines of the execution_actions input.
+func parseExecutionScripts(input string) ([]executionScript, error) {
+	var scripts []executionScript
+	for _, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		key, scriptPath, found := strings.Cut(line, ":")
+		if !found {
+			return nil, fmt.Errorf("invalid execution action (%s), expected format: <action>_<phase>: <script path>", line)
+		}
+
+		action, phase, found := strings.Cut(strings.TrimSpace(key), "_")
+		if !found {
+			return nil, fmt.Errorf("invalid execution action key (%s), expected format: <action>_<phase>", key)
+		}
+
+		script := executionScript{
+			Action:     schemeActionName(action),
+			Phase:      executionPhase(phase),
+			ScriptPath: strings.TrimSpace(scriptPath),
+		}
+
+		switch script.Action {
+		case buildSchemeAction, testSchemeAction, archiveSchemeAction:
+		default:
+			return nil, fmt.Errorf("unsupported scheme action (%s) in execution action (%s), available actions: %s, %s, %s", action, line, buildSchemeAction, testSchemeAction, archiveSchemeAction)
+		}
+
+		switch script.Phase {
+		case preExecutionPhase, postExecutionPhase:
+		default:
+			return nil, fmt.Errorf("unsupported phase (%s) in execution action (%s), available phases: %s, %s", phase, line, preExecutionPhase, postExecutionPhase)
+		}
+
+		if script.ScriptPath == "" {
+			return nil, fmt.Errorf("missing script path in execution action (%s)", line)
+		}
+		content, err := os.ReadFile(script.ScriptPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read execution action script (%s): %w", script.ScriptPath, err)
+		}
+		if strings.TrimSpace(string(content)) == "" {
+			return nil, fmt.Errorf("execution action script (%s) is empty", script.ScriptPath)
+		}
+		script.ScriptText = string(content)
+
+		scripts = append(scripts, script)
+	}
+
+	return scripts, nil
+}
+
+// addExecutionScripts adds the scripts as Run Script actions, with the build settings of the scheme's main build target.
+func addExecutionScripts(scheme generatedScheme, scripts []executionScript) generatedScheme {
+	var buildable *environmentBuildable
+	if reference, ok := scheme.buildableReference(); ok {
+		buildable = &environmentBuildable{BuildableReference: reference}
+	}
+
+	for _, script := range scripts {
+		action := executionAction{
+			ActionType: shellScriptActionType,
+			ActionContent: shellScriptActionContent{
+				Title:                filepath.Base(script.ScriptPath),
+				ScriptText:           script.ScriptText,
+				ShellToRunWith:       "/bin/sh",
+				EnvironmentBuildable: buildable,
+			},
+		}
+
+		var actions *schemeExecutionActions
+		switch script.Action {
+		case buildSchemeAction:
+			actions = &scheme.BuildExecutionActions
+		case testSchemeAction:
+			actions = &scheme.TestExecutionActions
+		case archiveSchemeAction:
+			actions = &scheme.ArchiveExecutionActions
+		}
+
+		if script.Phase == preExecutionPhase {
+			actions.Pre = append(actions.Pre, action)
+		} else {
+			actions.Post = append(actions.Post, action)
+		}
+	}
+
+	return scheme
+}
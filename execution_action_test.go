@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseExecutionScripts(t *testing.T) {
+	dir := t.TempDir()
+	scriptPath := filepath.Join(dir, "generate_config.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/sh\necho config\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	emptyPath := filepath.Join(dir, "empty.sh")
+	if err := os.WriteFile(emptyPath, []byte("\n  \n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []executionScript
+		wantErr string
+	}{
+		{
+			name:  "pre and post actions",
+			input: "build_pre: " + scriptPath + "\n\n  archive_post:" + scriptPath + "  \n",
+			want: []executionScript{
+				{Action: buildSchemeAction, Phase: preExecutionPhase, ScriptPath: scriptPath, ScriptText: "#!/bin/sh\necho config\n"},
+				{Action: archiveSchemeAction, Phase: postExecutionPhase, ScriptPath: scriptPath, ScriptText: "#!/bin/sh\necho config\n"},
+			},
+		},
+		{
+			name:  "empty input",
+			input: "\n",
+		},
+		{
+			name:    "missing separator",
+			input:   "build_pre " + scriptPath,
+			wantErr: "invalid execution action",
+		},
+		{
+			name:    "missing phase",
+			input:   "build: " + scriptPath,
+			wantErr: "invalid execution action key (build)",
+		},
+		{
+			name:    "invalid action",
+			input:   "launch_pre: " + scriptPath,
+			wantErr: "unsupported scheme action (launch)",
+		},
+		{
+			name:    "invalid phase",
+			input:   "build_during: " + scriptPath,
+			wantErr: "unsupported phase (during)",
+		},
+		{
+			name:    "missing script path",
+			input:   "test_pre:",
+			wantErr: "missing script path in execution action (test_pre:)",
+		},
+		{
+			name:    "missing script",
+			input:   "test_pre: " + filepath.Join(dir, "missing.sh"),
+			wantErr: "failed to read execution action script",
+		},
+		{
+			name:    "empty script",
+			input:   "test_post: " + emptyPath,
+			wantErr: "is empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseExecutionScripts(tt.input)
+			checkError(t, err, tt.wantErr)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseExecutionScripts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
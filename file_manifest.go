@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	manifestFileName = "recreate_user_schemes_manifest.json"
+	// manifestDirName is the directory of the manifest in the write root, if manifest_path is not set.
+	// The manifest holds the original contents of the overwritten files, so it is kept out of the deploy directory,
+	// and out of git through the .gitignore file written next to it.
+	manifestDirName = ".recreate_user_schemes"
+)
+
+// defaultManifestPath returns the manifest path used if manifest_path is not set.
+func defaultManifestPath(writeRoot string) string {
+	return filepath.Join(writeRoot, manifestDirName, manifestFileName)
+}
+
+// isDefaultManifestDir tells if the directory is the step's own manifest directory, which can be ignored and removed as a whole.
+func isDefaultManifestDir(dir string) bool {
+	return filepath.Base(dir) == manifestDirName
+}
+
+// fileManifest records the first change of every written path for cleanup mode, a nil manifest records nothing.
+type fileManifest struct {
+	Entries []manifestEntry `json:"entries"`
+
+	path    string
+	changed bool
+}
+
+type manifestEntry struct {
+	Path  string `json:"path"`
+	IsDir bool   `json:"is_dir"`
+	// Existed is true if the file existed before the step changed it, it is restored with its original contents and mode.
+	// Otherwise the file or directory is removed on cleanup.
+	Existed  bool        `json:"existed"`
+	Contents []byte      `json:"contents,omitempty"`
+	Mode     os.FileMode `json:"mode,omitempty"`
+}
+
+// openFileManifest reads the manifest at the given path, the manifest is empty if the file does not exist.
+func openFileManifest(pth string) (*fileManifest, error) {
+	manifest := &fileManifest{path: pth}
+
+	contents, err := os.ReadFile(pth)
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	if err := json.Unmarshal(contents, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest (%s): %w", pth, err)
+	}
+
+	return manifest, nil
+}
+
+func (m *fileManifest) isRecorded(pth string) bool {
+	for _, entry := range m.Entries {
+		if entry.Path == pth {
+			return true
+		}
+	}
+	return false
+}
+
+// recordFile records the state of the file before the step creates or overwrites it.
+func (m *fileManifest) recordFile(pth string) error {
+	if m == nil || m.isRecorded(pth) {
+		return nil
+	}
+
+	entry := manifestEntry{Path: pth}
+	info, err := os.Lstat(pth)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		contents, err := os.ReadFile(pth)
+		if err != nil {
+			return err
+		}
+		entry.Existed = true
+		entry.Contents = contents
+		entry.Mode = info.Mode().Perm()
+	}
+
+	m.Entries = append(m.Entries, entry)
+	m.changed = true
+	return nil
+}
+
+// recordCreatedDir records a directory the step created.
+func (m *fileManifest) recordCreatedDir(pth string) {
+	if m == nil || m.isRecorded(pth) {
+		return
+	}
+
+	m.Entries = append(m.Entries, manifestEntry{Path: pth, IsDir: true})
+	m.changed = true
+}
+
+// save writes the manifest, if anything is recorded since it was opened.
+func (m *fileManifest) save() error {
+	if m == nil || !m.changed {
+		return nil
+	}
+
+	contents, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	dir := filepath.Dir(m.path)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+	if isDefaultManifestDir(dir) {
+		if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*\n"), 0600); err != nil {
+			return fmt.Errorf("failed to write the .gitignore of the manifest directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(m.path, append(contents, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	m.changed = false
+
+	fmt.Println()
+	log.Printf("Manifest of the changed files saved to: %s", m.path)
+
+	return nil
+}
+
+// restore undoes the recorded changes inside the write root in reverse order, and returns the number of restored paths.
+func (m *fileManifest) restore(containerPath, writeRoot string) (int, error) {
+	var restored int
+	var errs []error
+	for i := len(m.Entries) - 1; i >= 0; i-- {
+		entry := m.Entries[i]
+		relPath := pathRelativeToWorkspace(entry.Path, containerPath)
+
+		if err := checkWritePath(writeRoot, entry.Path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		switch {
+		case entry.Existed:
+			if err := os.WriteFile(entry.Path, entry.Contents, entry.Mode); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore %s: %w", entry.Path, err))
+				continue
+			}
+			// WriteFile keeps the mode of an existing file.
+			if err := os.Chmod(entry.Path, entry.Mode); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore the mode of %s: %w", entry.Path, err))
+				continue
+			}
+			log.Printf("- %s: restored", relPath)
+		case entry.IsDir:
+			files, err := os.ReadDir(entry.Path)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if len(files) > 0 {
+				log.Warnf("- %s: kept, it contains files not created by the step", relPath)
+				continue
+			}
+			if err := os.Remove(entry.Path); err != nil {
+				errs = append(errs, fmt.Errorf("failed to remove %s: %w", entry.Path, err))
+				continue
+			}
+			log.Printf("- %s: removed", relPath)
+		default:
+			if err := os.Remove(entry.Path); err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					errs = append(errs, fmt.Errorf("failed to remove %s: %w", entry.Path, err))
+				}
+				continue
+			}
+			log.Printf("- %s: removed", relPath)
+		}
+		restored++
+	}
+
+	return restored, errors.Join(errs...)
+}
+
+// cleanup restores the files and directories recorded in the manifest, and removes the manifest.
+func (g SchemeGenerator) cleanup(cfg Config) error {
+	if cfg.Manifest == nil {
+		return fmt.Errorf("cleanup mode requires a manifest")
+	}
+
+	fmt.Println()
+	log.Infof("Restoring the files changed by the step...")
+
+	if len(cfg.Manifest.Entries) == 0 {
+		log.Donef("No changed files recorded in %s.", cfg.Manifest.path)
+		return nil
+	}
+
+	if cfg.DryRun {
+		for i := len(cfg.Manifest.Entries) - 1; i >= 0; i-- {
+			entry := cfg.Manifest.Entries[i]
+			action := "removed"
+			if entry.Existed {
+				action = "restored"
+			}
+			log.Printf("- %s: would be %s", pathRelativeToWorkspace(entry.Path, cfg.ContainerPath), action)
+		}
+		fmt.Println()
+		log.Donef("Dry run, nothing is restored.")
+		return nil
+	}
+
+	restored, err := cfg.Manifest.restore(cfg.ContainerPath, cfg.WriteRoot)
+	if err != nil {
+		// The manifest is kept, so the cleanup can be retried.
+		return fmt.Errorf("restoring the changed files failed: %w", err)
+	}
+
+	if err := os.Remove(cfg.Manifest.path); err != nil {
+		return fmt.Errorf("failed to remove manifest: %w", err)
+	}
+	if dir := filepath.Dir(cfg.Manifest.path); isDefaultManifestDir(dir) {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to remove the manifest directory: %w", err)
+		}
+	}
+	cfg.Manifest.Entries = nil
+
+	fmt.Println()
+	log.Donef("Restored %d path(s).", restored)
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultManifestRoundTrip(t *testing.T) {
+	writeRoot := t.TempDir()
+	schemePath := filepath.Join(writeRoot, "App.xcodeproj", "xcshareddata", "xcschemes", "App.xcscheme")
+	if err := os.MkdirAll(filepath.Dir(schemePath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(schemePath, []byte("original"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := openFileManifest(defaultManifestPath(writeRoot))
+	if err != nil {
+		t.Fatalf("openFileManifest() error = %v", err)
+	}
+	if err := manifest.recordFile(schemePath); err != nil {
+		t.Fatalf("recordFile() error = %v", err)
+	}
+	if err := os.WriteFile(schemePath, []byte("generated"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := manifest.save(); err != nil {
+		t.Fatalf("save() error = %v", err)
+	}
+
+	manifestDir := filepath.Join(writeRoot, manifestDirName)
+	if content, err := os.ReadFile(filepath.Join(manifestDir, ".gitignore")); err != nil || string(content) != "*\n" {
+		t.Fatalf(".gitignore = %q, %v, want the whole directory ignored", content, err)
+	}
+
+	reopened, err := openFileManifest(defaultManifestPath(writeRoot))
+	if err != nil {
+		t.Fatalf("openFileManifest() error = %v", err)
+	}
+	cfg := Config{ContainerPath: filepath.Join(writeRoot, "App.xcodeproj"), WriteRoot: writeRoot, Manifest: reopened}
+	if err := (SchemeGenerator{}).cleanup(cfg); err != nil {
+		t.Fatalf("cleanup() error = %v", err)
+	}
+
+	if content, err := os.ReadFile(schemePath); err != nil || string(content) != "original" {
+		t.Errorf("restored scheme = %q, %v, want the original contents", content, err)
+	}
+	if _, err := os.Stat(manifestDir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("manifest directory is not removed: %v", err)
+	}
+}
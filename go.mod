@@ -3,14 +3,16 @@ module github.com/bitrise-steplib/steps-recreate-user-schemes
 go 1.20
 
 require (
+	github.com/bitrise-io/go-plist v0.0.0-20210301100253-4b1a112ccd10
 	github.com/bitrise-io/go-steputils/v2 v2.0.0-alpha.1
 	github.com/bitrise-io/go-utils v1.0.9
 	github.com/bitrise-io/go-utils/v2 v2.0.0-alpha.1
 	github.com/bitrise-io/go-xcode v1.0.16
+	golang.org/x/text v0.12.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/bitrise-io/go-plist v0.0.0-20210301100253-4b1a112ccd10 // indirect
-	golang.org/x/text v0.12.0 // indirect
+	github.com/bitrise-io/go-steputils v1.0.5 // indirect
 	howett.net/plist v1.0.0 // indirect
 )
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/bitrise-io/go-steputils/v2/stepconf"
+	"github.com/bitrise-io/go-steputils/v2/stepenv"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/v2/env"
 )
@@ -21,8 +22,20 @@ func run() int {
 
 	}
 
-	if err := s.Run(cfg); err != nil {
-		log.Errorf("Run: %s", err)
+	result, runErr := s.Run(cfg)
+	// If some of multiple containers failed, the outputs and the report of the others are still exported.
+	if runErr != nil && len(result.Containers) == 0 {
+		log.Errorf("Run: %s", runErr)
+		return 1
+	}
+
+	if err := s.ExportOutputs(cfg, result); err != nil {
+		log.Errorf("Export outputs: %s", err)
+		return 1
+	}
+
+	if runErr != nil {
+		log.Errorf("Run: %s", runErr)
 		return 1
 	}
 
@@ -30,6 +43,7 @@ func run() int {
 }
 
 func createStep() SchemeGenerator {
-	inputParser := stepconf.NewInputParser(env.NewRepository())
-	return NewSchemeGenerator(inputParser)
+	envRepository := env.NewRepository()
+	inputParser := stepconf.NewInputParser(envRepository)
+	return NewSchemeGenerator(inputParser, stepenv.NewRepository(envRepository))
 }
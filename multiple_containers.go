@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+// containerResult is the result of one of the processed containers.
+type containerResult struct {
+	cfg    Config
+	result Result
+	// skipped is set if every project of the container is processed with an earlier container.
+	skipped bool
+	err     error
+}
+
+const (
+	containerSucceeded = "succeeded"
+	containerSkipped   = "skipped"
+	containerFailed    = "failed"
+)
+
+// status returns whether the container succeeded, failed or was skipped.
+func (r containerResult) status() string {
+	switch {
+	case r.err != nil:
+		return containerFailed
+	case r.skipped:
+		return containerSkipped
+	default:
+		return containerSucceeded
+	}
+}
+
+// assignProjects returns the projects processed with an earlier container, and the number of the container's own projects.
+func assignProjects(cfg Config, containerPath string, owners map[string]string) (map[string]string, int) {
+	container, err := schemelist.Open(containerPath, cfg.Projects, nil)
+	if err != nil {
+		return nil, 0
+	}
+
+	projects, _, _ := container.Projects()
+	excluded := map[string]string{}
+	for _, project := range projects {
+		if owner, ok := owners[project.Path]; ok && owner != containerPath {
+			excluded[project.Path] = owner
+			continue
+		}
+		owners[project.Path] = containerPath
+	}
+	return excluded, len(projects) - len(excluded)
+}
+
+// runMultiple runs the configured mode for every container, a shared project is processed with the first one.
+func (g SchemeGenerator) runMultiple(cfg Config) (Result, error) {
+	owners := map[string]string{}
+	var results []containerResult
+	var errs []error
+	for i, containerPath := range cfg.ContainerPaths {
+		containerCfg := cfg
+		containerCfg.ContainerPath = containerPath
+
+		fmt.Println()
+		log.Infof("Container %d/%d: %s", i+1, len(cfg.ContainerPaths), containerPath)
+
+		excluded, ownProjects := assignProjects(cfg, containerPath, owners)
+		containerCfg.ExcludedProjects = excluded
+		for _, projectPath := range sortedKeys(excluded) {
+			log.Printf("Project %s is processed with %s", pathRelativeToWorkspace(projectPath, containerPath), filepath.Base(excluded[projectPath]))
+		}
+
+		// Listing is read-only, every container is listed.
+		if ownProjects == 0 && len(excluded) > 0 && cfg.Mode != listMode && cfg.Mode != cleanupMode {
+			log.Donef("Every project of %s is processed with an earlier container, skipping it.", filepath.Base(containerPath))
+			results = append(results, containerResult{cfg: containerCfg, skipped: true, result: Result{
+				Warnings: []string{"skipped: every project is processed with an earlier container"},
+			}})
+			continue
+		}
+
+		result, err := g.run(containerCfg)
+		if err != nil {
+			fmt.Println()
+			log.Errorf("%s failed: %s", filepath.Base(containerPath), err)
+			errs = append(errs, fmt.Errorf("%s: %w", containerPath, err))
+		}
+		results = append(results, containerResult{cfg: containerCfg, result: result, err: err})
+	}
+
+	merged := mergeContainerResults(results)
+
+	fmt.Println()
+	log.Infof("Summary:")
+	for _, r := range results {
+		switch {
+		case r.err != nil:
+			log.Errorf("- %s: failed: %s", r.cfg.ContainerPath, r.err)
+		case len(r.result.GeneratedSchemes) > 0:
+			log.Donef("- %s: %d Scheme(s) generated", r.cfg.ContainerPath, len(r.result.GeneratedSchemes))
+		default:
+			log.Donef("- %s: no Scheme generated", r.cfg.ContainerPath)
+		}
+	}
+
+	var listErr error
+	if cfg.Mode == listMode {
+		listErr = saveContainerLists(cfg, results)
+	}
+
+	if len(errs) > 0 {
+		return merged, fmt.Errorf("%d of %d containers failed: %w", len(errs), len(cfg.ContainerPaths), errors.Join(append(errs, listErr)...))
+	}
+	return merged, listErr
+}
+
+// saveContainerLists saves the lists of the listed containers together, as a JSON array.
+func saveContainerLists(cfg Config, results []containerResult) error {
+	var lists []schemelist.List
+	for _, r := range results {
+		if r.result.List != nil {
+			lists = append(lists, *r.result.List)
+		}
+	}
+	contents, err := json.MarshalIndent(lists, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal list: %w", err)
+	}
+	return saveList(cfg, append(contents, '\n'))
+}
+
+// mergeContainerResults combines the results of the succeeded containers, the failed ones are only kept for the report.
+func mergeContainerResults(results []containerResult) Result {
+	merged := Result{
+		ContainerToSchemes: map[string][]xcscheme.Scheme{},
+		Containers:         results,
+	}
+
+	var copyPaths []string
+	for _, r := range results {
+		if r.err != nil {
+			continue
+		}
+		for containerPath, schemes := range r.result.ContainerToSchemes {
+			merged.ContainerToSchemes[containerPath] = schemes
+		}
+		merged.GeneratedSchemes = append(merged.GeneratedSchemes, r.result.GeneratedSchemes...)
+		merged.RegeneratedSchemes = append(merged.RegeneratedSchemes, r.result.RegeneratedSchemes...)
+		merged.GeneratedSchemePaths = append(merged.GeneratedSchemePaths, r.result.GeneratedSchemePaths...)
+		merged.RegeneratedSchemePaths = append(merged.RegeneratedSchemePaths, r.result.RegeneratedSchemePaths...)
+		if merged.PrimaryScheme == "" {
+			merged.PrimaryScheme = r.result.PrimaryScheme
+		}
+		if r.result.ContainerCopyPath != "" {
+			copyPaths = append(copyPaths, r.result.ContainerCopyPath)
+		}
+		for _, warning := range r.result.Warnings {
+			merged.Warnings = append(merged.Warnings, fmt.Sprintf("%s: %s", filepath.Base(r.cfg.ContainerPath), warning))
+		}
+	}
+	merged.ContainerCopyPath = strings.Join(copyPaths, "\n")
+
+	return merged
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+)
+
+func TestPartiallyFailedContainers(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join("testdata", "xcodebuild_list"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	appPath := filepath.Join(dir, "Empty.xcodeproj")
+	failedPath := filepath.Join(dir, "Missing.xcworkspace")
+
+	results := []containerResult{
+		{
+			cfg: Config{ContainerPath: appPath},
+			result: Result{
+				ContainerToSchemes: map[string][]xcscheme.Scheme{appPath: {{Name: "App"}}},
+				GeneratedSchemes:   []string{"App"},
+				PrimaryScheme:      "App",
+			},
+		},
+		{
+			cfg:    Config{ContainerPath: failedPath},
+			result: Result{ContainerToSchemes: map[string][]xcscheme.Scheme{failedPath: {{Name: "Partial"}}}, GeneratedSchemes: []string{"Partial"}},
+			err:    errors.New("opening container failed"),
+		},
+	}
+
+	merged := mergeContainerResults(results)
+	if !reflect.DeepEqual(merged.GeneratedSchemes, []string{"App"}) || len(merged.ContainerToSchemes) != 1 || merged.PrimaryScheme != "App" {
+		t.Errorf("merged result = %+v, want only the schemes of the succeeded container", merged)
+	}
+
+	cfg := Config{ContainerPaths: []string{appPath, failedPath}, DeployDir: t.TempDir()}
+	if err := writeReport(cfg, merged); err != nil {
+		t.Fatalf("writeReport() error = %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(cfg.DeployDir, reportFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reports struct {
+		Containers []struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		} `json:"containers"`
+	}
+	if err := json.Unmarshal(content, &reports); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct{ status, err string }{
+		{containerSucceeded, ""},
+		{containerFailed, "opening container failed"},
+	}
+	if len(reports.Containers) != len(want) {
+		t.Fatalf("report has %d containers, want %d", len(reports.Containers), len(want))
+	}
+	for i, w := range want {
+		if got := reports.Containers[i]; got.Status != w.status || got.Error != w.err {
+			t.Errorf("container %d = %s (%q), want %s (%q)", i+1, got.Status, got.Error, w.status, w.err)
+		}
+	}
+}
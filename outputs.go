@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+const (
+	generatedSchemesOutputKey  = "BITRISE_GENERATED_SCHEMES"
+	sharedSchemesOutputKey     = "BITRISE_SHARED_SCHEMES"
+	sharedSchemePathsOutputKey = "BITRISE_SHARED_SCHEME_PATHS"
+	schemesGeneratedOutputKey  = "BITRISE_SCHEMES_GENERATED"
+	primarySchemeOutputKey     = "BITRISE_SCHEME"
+	containerCopyPathOutputKey = "BITRISE_SCHEMES_CONTAINER_COPY_PATH"
+	projectPathOutputKey       = "BITRISE_PROJECT_PATH"
+)
+
+// Result is what the step found and generated.
+type Result struct {
+	// ContainerToSchemes are the schemes of the container after the step ran.
+	ContainerToSchemes map[string][]xcscheme.Scheme
+	GeneratedSchemes   []string
+	// RegeneratedSchemes are the generated schemes replacing malformed scheme files.
+	RegeneratedSchemes []string
+	// GeneratedSchemePaths are the written scheme files, in the output directory if it is set.
+	GeneratedSchemePaths []string
+	// RegeneratedSchemePaths are the written scheme files replacing malformed scheme files.
+	RegeneratedSchemePaths []string
+	// PrimaryScheme is the requested scheme if the scheme input is set, otherwise the best ranked generated or regenerated scheme.
+	// It is empty if there is no such scheme.
+	PrimaryScheme string
+	// ContainerCopyPath is the copy of the container with the generated schemes, empty if the container is not copied.
+	ContainerCopyPath string
+	// FilteredTargets are the targets removed by the target filters, no scheme is generated for them.
+	FilteredTargets []filteredTarget
+	Warnings        []string
+	// List is the `xcodebuild -list -json` equivalent of the container in list mode.
+	List *schemelist.List
+	// Containers are the results of the containers one by one, if multiple containers are processed.
+	Containers []containerResult
+}
+
+// ExportOutputs exports the step outputs, and saves the report and the patches to the deploy directory if set.
+func (g SchemeGenerator) ExportOutputs(cfg Config, result Result) error {
+	var sharedSchemes, sharedSchemePaths []string
+	for _, containerPath := range sortedContainerPaths(result.ContainerToSchemes) {
+		for _, scheme := range result.ContainerToSchemes[containerPath] {
+			if !scheme.IsShared || scheme.Path == "" {
+				// Autocreated default schemes have no scheme file.
+				continue
+			}
+			sharedSchemes = append(sharedSchemes, scheme.Name)
+			sharedSchemePaths = append(sharedSchemePaths, scheme.Path)
+		}
+	}
+
+	outputs := []struct{ key, value string }{
+		{generatedSchemesOutputKey, strings.Join(result.GeneratedSchemes, "\n")},
+		{sharedSchemesOutputKey, strings.Join(sharedSchemes, "\n")},
+		{sharedSchemePathsOutputKey, strings.Join(sharedSchemePaths, "\n")},
+		{schemesGeneratedOutputKey, strconv.FormatBool(len(result.GeneratedSchemes) > 0)},
+	}
+	if result.PrimaryScheme != "" {
+		// BITRISE_SCHEME is usually set by the workflow, it is only overridden if the step generated the schemes or made sure the requested one is shared.
+		outputs = append(outputs, struct{ key, value string }{primarySchemeOutputKey, result.PrimaryScheme})
+	}
+	if cfg.ContainerPathCorrected {
+		// BITRISE_PROJECT_PATH is usually set by the workflow, it is only overridden if the step used a different container.
+		outputs = append(outputs, struct{ key, value string }{projectPathOutputKey, cfg.ContainerPath})
+	}
+	if result.ContainerCopyPath != "" {
+		outputs = append(outputs, struct{ key, value string }{containerCopyPathOutputKey, result.ContainerCopyPath})
+	}
+
+	fmt.Println()
+	log.Infof("Exporting outputs...")
+	for _, output := range outputs {
+		if err := g.envRepository.Set(output.key, output.value); err != nil {
+			return fmt.Errorf("failed to export %s: %w", output.key, err)
+		}
+		log.Donef("$%s = %s", output.key, strings.ReplaceAll(output.value, "\n", ", "))
+	}
+
+	if cfg.DeployDir != "" {
+		if err := writeReport(cfg, result); err != nil {
+			return err
+		}
+		if len(result.GeneratedSchemes) > 0 {
+			if err := writeSchemePatch(cfg, result); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
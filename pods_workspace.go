@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcworkspace"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+var podfileNames = []string{"Podfile", "Podfile.lock"}
+
+// podsWorkspace returns the CocoaPods workspace next to the project, which references it and has a Podfile (or Podfile.lock) beside it.
+func podsWorkspace(projectPath string) (string, bool, error) {
+	if !xcodeproject.IsXcodeProj(projectPath) {
+		return "", false, nil
+	}
+	dir := filepath.Dir(projectPath)
+
+	hasPodfile := false
+	for _, name := range podfileNames {
+		exists, err := pathutil.IsPathExists(filepath.Join(dir, name))
+		if err != nil {
+			return "", false, err
+		}
+		hasPodfile = hasPodfile || exists
+	}
+	if !hasPodfile {
+		return "", false, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false, err
+	}
+	for _, entry := range entries {
+		workspacePath := filepath.Join(dir, entry.Name())
+		if !entry.IsDir() || !xcworkspace.IsWorkspace(workspacePath) {
+			continue
+		}
+
+		workspace, err := xcworkspace.Open(workspacePath)
+		if err != nil {
+			log.Warnf("Failed to open workspace %s: %s", workspacePath, err)
+			continue
+		}
+		projectPaths, err := schemelist.WorkspaceProjectLocations(workspace)
+		if err != nil {
+			log.Warnf("Failed to list the projects of workspace %s: %s", workspacePath, err)
+			continue
+		}
+		for _, pth := range projectPaths {
+			if pth == projectPath {
+				return workspacePath, true, nil
+			}
+		}
+	}
+
+	return "", false, nil
+}
+
+// checkPodsWorkspace warns if the project is part of a CocoaPods workspace, and returns the workspace if the step should switch to it.
+func checkPodsWorkspace(projectPath string, switchToWorkspace bool) (string, bool, error) {
+	workspacePath, found, err := podsWorkspace(projectPath)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to check for the CocoaPods workspace of %s: %w", projectPath, err)
+	}
+	if !found {
+		return "", false, nil
+	}
+
+	fmt.Println()
+	log.Warnf("%s is part of the CocoaPods workspace %s.", filepath.Base(projectPath), filepath.Base(workspacePath))
+	log.Warnf("Schemes of the project alone do not build the Pods, so builds of the project fail to link them.")
+	if !switchToWorkspace {
+		log.Warnf("Set project_path to %s, or enable switch_to_pods_workspace.", workspacePath)
+		return "", false, nil
+	}
+
+	log.Printf("Switching to %s", workspacePath)
+	return workspacePath, true, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+// The scores of the primary scheme preferences, each outweighs all the weaker ones together.
+const (
+	appProductScore    = 8
+	testableScore      = 4
+	archivableScore    = 2
+	containerNameScore = 1
+)
+
+// schemeRank is the score of a scheme as the primary scheme of the container, with the reasons of the score.
+type schemeRank struct {
+	Scheme  xcscheme.Scheme
+	Score   int
+	Reasons []string
+}
+
+// rankSchemes orders the schemes by their preference as the primary scheme, keeping the order of ties.
+func rankSchemes(containerPath string, containerToSchemes map[string][]xcscheme.Scheme, names []string, projects []xcodeproject.XcodeProj) []schemeRank {
+	var ranks []schemeRank
+	for _, schemeContainerPath := range sortedContainerPaths(containerToSchemes) {
+		for _, scheme := range containerToSchemes[schemeContainerPath] {
+			if !containsSchemeName(names, scheme.Name) {
+				continue
+			}
+
+			rank := schemeRank{Scheme: scheme}
+			addPreference := func(ok bool, score int, reason string) {
+				if ok {
+					rank.Score += score
+					rank.Reasons = append(rank.Reasons, reason)
+				}
+			}
+
+			target, ok := schemeMainTarget(scheme, schemeContainerPath, projects)
+			addPreference(ok && target.IsAppProduct(), appProductScore, "app product")
+			addPreference(scheme.IsTestable(), testableScore, "testable")
+			_, archivable := scheme.AppBuildActionEntry()
+			addPreference(archivable, archivableScore, "archivable")
+			addPreference(schemelist.IsSameSchemeName(scheme.Name, containerName(containerPath)) || schemelist.IsSameSchemeName(scheme.Name, containerName(schemeContainerPath)), containerNameScore, "matches the project name")
+
+			ranks = append(ranks, rank)
+		}
+	}
+
+	sort.SliceStable(ranks, func(i, j int) bool {
+		return ranks[i].Score > ranks[j].Score
+	})
+
+	return ranks
+}
+
+// schemeMainTarget returns the target of the scheme's first build action entry.
+func schemeMainTarget(scheme xcscheme.Scheme, containerPath string, projects []xcodeproject.XcodeProj) (xcodeproject.Target, bool) {
+	reference, ok := newGeneratedScheme(scheme).buildableReference()
+	if !ok {
+		return xcodeproject.Target{}, false
+	}
+
+	projectPath, err := reference.ReferencedContainerAbsPath(filepath.Dir(containerPath))
+	if err != nil {
+		return xcodeproject.Target{}, false
+	}
+
+	for _, project := range projects {
+		if project.Path == projectPath {
+			return project.Proj.Target(reference.BlueprintIdentifier)
+		}
+	}
+	return xcodeproject.Target{}, false
+}
+
+func containerName(pth string) string {
+	return strings.TrimSuffix(filepath.Base(pth), filepath.Ext(pth))
+}
+
+func containsSchemeName(names []string, name string) bool {
+	for _, n := range names {
+		if schemelist.IsSameSchemeName(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// primaryScheme ranks the given schemes and returns the best ranked one, empty if none of them is found.
+func primaryScheme(containerPath string, containerToSchemes map[string][]xcscheme.Scheme, names []string, projects []xcodeproject.XcodeProj) string {
+	ranks := rankSchemes(containerPath, containerToSchemes, names, projects)
+	printSchemeRanks(ranks)
+	if len(ranks) == 0 {
+		return ""
+	}
+	return ranks[0].Scheme.Name
+}
+
+func printSchemeRanks(ranks []schemeRank) {
+	fmt.Println()
+	log.Infof("Ranking the generated Schemes...")
+	for i, rank := range ranks {
+		reasons := "-"
+		if len(rank.Reasons) > 0 {
+			reasons = strings.Join(rank.Reasons, ", ")
+		}
+		log.Printf("%d. %s (score: %d): %s", i+1, rank.Scheme.Name, rank.Score, reasons)
+	}
+
+	if len(ranks) > 0 {
+		log.Donef("Primary Scheme: %s", ranks[0].Scheme.Name)
+	}
+}
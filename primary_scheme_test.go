@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+)
+
+func newTestRankedScheme(target xcodeproject.Target, testable bool) xcscheme.Scheme {
+	scheme := xcscheme.Scheme{
+		Name:        target.Name,
+		BuildAction: newBuildAction([]xcodeproject.Target{target}, "App.xcodeproj"),
+	}
+	if testable {
+		scheme.TestAction.Testables = []xcscheme.TestableReference{{
+			Skipped:            no,
+			BuildableReference: xcscheme.BuildableReference{BuildableName: target.Name + "Tests.xctest"},
+		}}
+	}
+	return scheme
+}
+
+func TestRankSchemes(t *testing.T) {
+	const appType = "com.apple.product-type.application"
+	const extensionType = "com.apple.product-type.app-extension"
+
+	app := newTestTarget("A1", "App", appType)
+	staging := newTestTarget("A2", "Staging", appType)
+	widget := newTestTarget("A3", "Widget", extensionType)
+	widget.ProductReference.Path = "Widget.appex"
+	project := newTestProject("/repo/App.xcodeproj", app, staging, widget)
+
+	containerToSchemes := map[string][]xcscheme.Scheme{
+		project.Path: {
+			// A testable extension named after the workspace still loses to any app.
+			newTestRankedScheme(widget, true),
+			newTestRankedScheme(staging, false),
+			newTestRankedScheme(app, true),
+		},
+	}
+	names := []string{"Widget", "Staging", "App"}
+
+	ranks := rankSchemes("/repo/Widget.xcworkspace", containerToSchemes, names, []xcodeproject.XcodeProj{project})
+
+	want := []struct {
+		name  string
+		score int
+	}{
+		{"App", appProductScore + testableScore + archivableScore + containerNameScore},
+		{"Staging", appProductScore + archivableScore},
+		{"Widget", testableScore + containerNameScore},
+	}
+	if len(ranks) != len(want) {
+		t.Fatalf("rankSchemes() returned %d ranks, want %d", len(ranks), len(want))
+	}
+	for i, w := range want {
+		if ranks[i].Scheme.Name != w.name || ranks[i].Score != w.score {
+			t.Errorf("rank %d = %s (%d), want %s (%d)", i+1, ranks[i].Scheme.Name, ranks[i].Score, w.name, w.score)
+		}
+	}
+
+	if got := primaryScheme("/repo/Widget.xcworkspace", containerToSchemes, []string{"Widget"}, []xcodeproject.XcodeProj{project}); got != "Widget" {
+		t.Errorf("primaryScheme() = %s, want the only listed scheme, Widget", got)
+	}
+}
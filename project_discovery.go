@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcworkspace"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+// discoveryIgnoredDirs are the dependency and build directories, skipped along with the hidden ones.
+var discoveryIgnoredDirs = map[string]bool{
+	"Pods":         true,
+	"Carthage":     true,
+	"node_modules": true,
+	"DerivedData":  true,
+}
+
+var errNoContainerFound = errors.New("no Xcode project or workspace found")
+
+// containerCandidate is a project or workspace found by the discovery.
+type containerCandidate struct {
+	path        string
+	isWorkspace bool
+	// depth is the number of directories between the searched directory and the container.
+	depth int
+}
+
+func (c containerCandidate) kind() string {
+	if c.isWorkspace {
+		return "workspace"
+	}
+	return "project"
+}
+
+// rankContainerCandidates sorts the candidates by depth, then by path, workspaces are not ranked above unrelated projects.
+func rankContainerCandidates(candidates []containerCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.depth != b.depth {
+			return a.depth < b.depth
+		}
+		return a.path < b.path
+	})
+}
+
+// findContainerCandidates returns the ranked projects and workspaces in the directory, without the projects of the found workspaces.
+func findContainerCandidates(dir string) ([]containerCandidate, error) {
+	var candidates []containerCandidate
+	if err := filepath.WalkDir(dir, func(pth string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() || pth == dir {
+			return nil
+		}
+
+		name := entry.Name()
+		if discoveryIgnoredDirs[name] || strings.HasPrefix(name, ".") {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(dir, pth)
+		if err != nil {
+			return err
+		}
+		depth := strings.Count(rel, string(filepath.Separator))
+
+		switch {
+		case xcworkspace.IsWorkspace(pth):
+			candidates = append(candidates, containerCandidate{path: pth, isWorkspace: true, depth: depth})
+			return filepath.SkipDir
+		case xcodeproject.IsXcodeProj(pth):
+			candidates = append(candidates, containerCandidate{path: pth, depth: depth})
+			return filepath.SkipDir
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to search for Xcode projects in %s: %w", dir, err)
+	}
+
+	workspaceProjects := map[string]bool{}
+	for _, candidate := range candidates {
+		if !candidate.isWorkspace {
+			continue
+		}
+		workspace, err := xcworkspace.Open(candidate.path)
+		if err != nil {
+			log.Warnf("Failed to open workspace %s: %s", candidate.path, err)
+			continue
+		}
+		projectPaths, err := schemelist.WorkspaceProjectLocations(workspace)
+		if err != nil {
+			log.Warnf("Failed to list the projects of workspace %s: %s", candidate.path, err)
+			continue
+		}
+		for _, projectPath := range projectPaths {
+			workspaceProjects[projectPath] = true
+		}
+	}
+
+	var filtered []containerCandidate
+	for _, candidate := range candidates {
+		if !candidate.isWorkspace && workspaceProjects[candidate.path] {
+			continue
+		}
+		filtered = append(filtered, candidate)
+	}
+
+	rankContainerCandidates(filtered)
+	return filtered, nil
+}
+
+// discoverContainer returns the best ranked candidate, it fails if the best ones are of the same depth.
+func discoverContainer(dir string) (string, error) {
+	fmt.Println()
+	log.Infof("Searching for the Xcode project or workspace in %s...", dir)
+
+	candidates, err := findContainerCandidates(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w in %s", errNoContainerFound, dir)
+	}
+
+	var b strings.Builder
+	for i, candidate := range candidates {
+		rel, err := filepath.Rel(dir, candidate.path)
+		if err != nil {
+			rel = candidate.path
+		}
+		fmt.Fprintf(&b, "\n%d. %s (%s)", i+1, rel, candidate.kind())
+	}
+
+	if len(candidates) > 1 && candidates[0].depth == candidates[1].depth {
+		return "", fmt.Errorf("found %d Xcode projects and workspaces in %s, set project_path to the one to use:%s", len(candidates), dir, b.String())
+	}
+
+	if len(candidates) > 1 {
+		log.Printf("Candidates, from the most likely:%s", b.String())
+	}
+	log.Donef("Using %s", candidates[0].path)
+
+	return candidates[0].path, nil
+}
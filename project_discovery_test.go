@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeTestDirs creates the given directories, for example project bundles.
+func makeTestDirs(t *testing.T, paths ...string) {
+	t.Helper()
+	for _, pth := range paths {
+		if err := os.MkdirAll(pth, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// writeTestWorkspace creates a workspace referencing the given locations, the referenced paths are not created.
+func writeTestWorkspace(t *testing.T, pth string, locations ...string) {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<Workspace\n   version = \"1.0\">\n")
+	for _, location := range locations {
+		b.WriteString("   <FileRef\n      location = \"" + location + "\">\n   </FileRef>\n")
+	}
+	b.WriteString("</Workspace>\n")
+
+	if err := os.MkdirAll(pth, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pth, "contents.xcworkspacedata"), []byte(b.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscoverContainer(t *testing.T) {
+	tests := []struct {
+		name string
+		// layout creates the searched directory's contents.
+		layout  func(t *testing.T, dir string)
+		want    string
+		wantErr string
+	}{
+		{
+			name: "workspace preferred over its project",
+			layout: func(t *testing.T, dir string) {
+				makeTestDirs(t, filepath.Join(dir, "App.xcodeproj"))
+				writeTestWorkspace(t, filepath.Join(dir, "App.xcworkspace"), "group:App.xcodeproj")
+			},
+			want: "App.xcworkspace",
+		},
+		{
+			name: "shallower project preferred over deeper one",
+			layout: func(t *testing.T, dir string) {
+				makeTestDirs(t, filepath.Join(dir, "ios", "App.xcodeproj"), filepath.Join(dir, "ios", "Modules", "Core.xcodeproj"))
+			},
+			want: filepath.Join("ios", "App.xcodeproj"),
+		},
+		{
+			name: "dependency and hidden directories ignored",
+			layout: func(t *testing.T, dir string) {
+				makeTestDirs(t,
+					filepath.Join(dir, "App.xcodeproj"),
+					filepath.Join(dir, "Pods", "Pods.xcodeproj"),
+					filepath.Join(dir, "Carthage", "Checkouts", "Lib", "Lib.xcodeproj"),
+					filepath.Join(dir, ".build", "checkouts", "Package.xcodeproj"),
+				)
+				writeTestWorkspace(t, filepath.Join(dir, "node_modules", "pkg", "Pkg.xcworkspace"))
+			},
+			want: "App.xcodeproj",
+		},
+		{
+			name: "embedded workspace is not a candidate",
+			layout: func(t *testing.T, dir string) {
+				makeTestDirs(t, filepath.Join(dir, "App.xcodeproj"))
+				writeTestWorkspace(t, filepath.Join(dir, "App.xcodeproj", "project.xcworkspace"), "self:")
+			},
+			want: "App.xcodeproj",
+		},
+		{
+			name: "ambiguous candidates",
+			layout: func(t *testing.T, dir string) {
+				makeTestDirs(t, filepath.Join(dir, "App.xcodeproj"), filepath.Join(dir, "Other.xcodeproj"))
+			},
+			wantErr: "found 2 Xcode projects and workspaces",
+		},
+		{
+			name: "project preferred over a deeper unrelated workspace",
+			layout: func(t *testing.T, dir string) {
+				makeTestDirs(t, filepath.Join(dir, "App.xcodeproj"), filepath.Join(dir, "Example", "Example.xcodeproj"))
+				writeTestWorkspace(t, filepath.Join(dir, "Example", "Example.xcworkspace"), "group:Example.xcodeproj")
+			},
+			want: "App.xcodeproj",
+		},
+		{
+			name: "unrelated workspace and project of the same depth",
+			layout: func(t *testing.T, dir string) {
+				makeTestDirs(t, filepath.Join(dir, "App.xcodeproj"), filepath.Join(dir, "Example.xcodeproj"))
+				writeTestWorkspace(t, filepath.Join(dir, "Example.xcworkspace"), "group:Example.xcodeproj")
+			},
+			wantErr: "found 2 Xcode projects and workspaces",
+		},
+		{
+			name:    "no candidate",
+			layout:  func(t *testing.T, dir string) { makeTestDirs(t, filepath.Join(dir, "Sources")) },
+			wantErr: errNoContainerFound.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.layout(t, dir)
+
+			got, err := discoverContainer(dir)
+			checkError(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+			if want := filepath.Join(dir, tt.want); got != want {
+				t.Errorf("discoverContainer() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestFindContainerCandidates(t *testing.T) {
+	dir := t.TempDir()
+	makeTestDirs(t,
+		filepath.Join(dir, "App.xcodeproj"),
+		filepath.Join(dir, "Tools", "Tools.xcodeproj"),
+		filepath.Join(dir, "ios", "Pods", "Pods.xcodeproj"),
+	)
+	writeTestWorkspace(t, filepath.Join(dir, "ios", "App.xcworkspace"), "group:../App.xcodeproj", "group:Pods/Pods.xcodeproj")
+
+	candidates, err := findContainerCandidates(dir)
+	if err != nil {
+		t.Fatalf("findContainerCandidates() error = %v", err)
+	}
+
+	// The projects of the workspace are left out, the workspace and the unrelated project of the same depth are ranked by path.
+	want := []containerCandidate{
+		{path: filepath.Join(dir, "Tools", "Tools.xcodeproj"), depth: 1},
+		{path: filepath.Join(dir, "ios", "App.xcworkspace"), isWorkspace: true, depth: 1},
+	}
+	if len(candidates) != len(want) {
+		t.Fatalf("findContainerCandidates() = %+v, want %+v", candidates, want)
+	}
+	for i := range want {
+		if candidates[i] != want[i] {
+			t.Errorf("candidate %d = %+v, want %+v", i+1, candidates[i], want[i])
+		}
+	}
+
+	if _, err := discoverContainer(filepath.Join(dir, "missing")); err == nil || errors.Is(err, errNoContainerFound) {
+		t.Errorf("discoverContainer() of a missing directory = %v, want a search error", err)
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+)
+
+const reportFileName = "recreate_user_schemes_report.json"
+
+// report describes the container, its projects, targets and schemes, with relative paths and stable ordering.
+type report struct {
+	Container       reportContainer `json:"container"`
+	Projects        []reportProject `json:"projects"`
+	MissingProjects []string        `json:"missing_projects"`
+	// FilteredTargets are the targets removed by the target filters, grouped by filter.
+	FilteredTargets []reportTargetFilter `json:"filtered_targets"`
+	Warnings        []string             `json:"warnings"`
+}
+
+type reportContainer struct {
+	Path          string         `json:"path"`
+	Type          string         `json:"type"`
+	SharedSchemes []reportScheme `json:"shared_schemes"`
+	UserSchemes   []reportScheme `json:"user_schemes"`
+}
+
+type reportProject struct {
+	Path             string         `json:"path"`
+	Targets          []reportTarget `json:"targets"`
+	SharedSchemes    []reportScheme `json:"shared_schemes"`
+	UserSchemes      []reportScheme `json:"user_schemes"`
+	GeneratedSchemes []reportScheme `json:"generated_schemes"`
+}
+
+type reportTarget struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	ProductType string `json:"product_type"`
+}
+
+type reportTargetFilter struct {
+	Filter  string                 `json:"filter"`
+	Targets []reportFilteredTarget `json:"targets"`
+}
+
+type reportFilteredTarget struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Project string `json:"project"`
+}
+
+type reportScheme struct {
+	Name string `json:"name"`
+	// Path is empty for the default schemes Xcode autocreates.
+	Path    string        `json:"path"`
+	Actions reportActions `json:"actions"`
+}
+
+type reportActions struct {
+	Build   reportBuildAction `json:"build"`
+	Test    reportTestAction  `json:"test"`
+	Launch  reportRunAction   `json:"launch"`
+	Profile reportRunAction   `json:"profile"`
+	Analyze reportAction      `json:"analyze"`
+	Archive reportAction      `json:"archive"`
+}
+
+type reportBuildAction struct {
+	Targets []reportBuildTarget `json:"targets"`
+}
+
+type reportBuildTarget struct {
+	reportReference
+	BuildFor []string `json:"build_for"`
+}
+
+type reportReference struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Product string `json:"product"`
+	Project string `json:"project"`
+}
+
+type reportTestAction struct {
+	Configuration string           `json:"configuration"`
+	Testables     []reportTestable `json:"testables"`
+	TestPlans     []string         `json:"test_plans"`
+}
+
+type reportTestable struct {
+	reportReference
+	Skipped bool `json:"skipped"`
+}
+
+type reportRunAction struct {
+	Configuration string           `json:"configuration"`
+	Runnable      *reportReference `json:"runnable"`
+}
+
+type reportAction struct {
+	Configuration string `json:"configuration"`
+}
+
+// containersReport is the report of multiple containers, the container paths are relative to their common directory.
+type containersReport struct {
+	Containers []containerReport `json:"containers"`
+}
+
+// containerReport is the report of one of multiple containers, listing the shared projects processed with another container.
+type containerReport struct {
+	report
+	// Status is succeeded, skipped (every project is processed with an earlier container) or failed.
+	Status         string                `json:"status"`
+	Error          string                `json:"error,omitempty"`
+	SharedProjects []reportSharedProject `json:"shared_projects"`
+}
+
+type reportSharedProject struct {
+	Path          string `json:"path"`
+	ProcessedWith string `json:"processed_with"`
+}
+
+// writeReport saves the JSON report of the container, or of every container if multiple containers are processed, to the deploy directory.
+func writeReport(cfg Config, result Result) error {
+	var v interface{}
+	if len(result.Containers) == 0 {
+		r, err := buildReport(cfg, result)
+		if err != nil {
+			return err
+		}
+		v = r
+	} else {
+		dir := containersDir(cfg.ContainerPaths)
+
+		reports := containersReport{Containers: []containerReport{}}
+		for _, c := range result.Containers {
+			r, err := buildReport(c.cfg, c.result)
+			if err != nil {
+				if c.err == nil {
+					return err
+				}
+				// The container failed before its projects could be read.
+				r = newReport(c.cfg.ContainerPath, nil, nil, c.result)
+			}
+			if rel, err := filepath.Rel(dir, c.cfg.ContainerPath); err == nil {
+				r.Container.Path = rel
+			}
+
+			shared := containerReport{report: r, Status: c.status(), SharedProjects: []reportSharedProject{}}
+			if c.err != nil {
+				shared.Error = c.err.Error()
+			}
+			for _, projectPath := range sortedKeys(c.cfg.ExcludedProjects) {
+				owner := c.cfg.ExcludedProjects[projectPath]
+				if rel, err := filepath.Rel(dir, owner); err == nil {
+					owner = rel
+				}
+				shared.SharedProjects = append(shared.SharedProjects, reportSharedProject{
+					Path:          pathRelativeToWorkspace(projectPath, c.cfg.ContainerPath),
+					ProcessedWith: owner,
+				})
+			}
+			reports.Containers = append(reports.Containers, shared)
+		}
+		v = reports
+	}
+
+	contents, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	pth := filepath.Join(cfg.DeployDir, reportFileName)
+	if err := os.WriteFile(pth, append(contents, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	fmt.Println()
+	log.Printf("Report saved to: %s", pth)
+
+	return nil
+}
+
+func buildReport(cfg Config, result Result) (report, error) {
+	container, err := openConfiguredContainer(cfg)
+	if err != nil {
+		return report{}, fmt.Errorf("opening container failed: %w", err)
+	}
+
+	projects, missingProjects, err := container.Projects()
+	if err != nil {
+		return report{}, fmt.Errorf("getting projects failed: %w", err)
+	}
+
+	return newReport(cfg.ContainerPath, projects, missingProjects, result), nil
+}
+
+func newReport(containerPath string, projects []xcodeproject.XcodeProj, missingProjects []string, result Result) report {
+	relPath := func(pth string) string {
+		return pathRelativeToWorkspace(pth, containerPath)
+	}
+
+	containerType := "project"
+	if filepath.Ext(containerPath) != xcodeproject.XcodeProjExtension {
+		containerType = "workspace"
+	}
+
+	r := report{
+		Container: reportContainer{
+			Path:          filepath.Base(containerPath),
+			Type:          containerType,
+			SharedSchemes: []reportScheme{},
+			UserSchemes:   []reportScheme{},
+		},
+		Projects:        []reportProject{},
+		MissingProjects: []string{},
+		FilteredTargets: []reportTargetFilter{},
+		Warnings:        []string{},
+	}
+
+	sortedProjects := append([]xcodeproject.XcodeProj{}, projects...)
+	sort.SliceStable(sortedProjects, func(i, j int) bool {
+		return sortedProjects[i].Path < sortedProjects[j].Path
+	})
+
+	for _, project := range sortedProjects {
+		p := reportProject{
+			Path:             relPath(project.Path),
+			Targets:          []reportTarget{},
+			SharedSchemes:    []reportScheme{},
+			UserSchemes:      []reportScheme{},
+			GeneratedSchemes: []reportScheme{},
+		}
+		for _, target := range project.Proj.Targets {
+			p.Targets = append(p.Targets, reportTarget{
+				ID:          target.ID,
+				Name:        target.Name,
+				Type:        string(target.Type),
+				ProductType: target.ProductType,
+			})
+		}
+
+		for _, scheme := range result.ContainerToSchemes[project.Path] {
+			s := newReportScheme(scheme, project.Path, relPath)
+			switch {
+			case scheme.IsShared && containsPath(result.GeneratedSchemePaths, scheme.Path):
+				p.GeneratedSchemes = append(p.GeneratedSchemes, s)
+			case scheme.IsShared:
+				p.SharedSchemes = append(p.SharedSchemes, s)
+			default:
+				p.UserSchemes = append(p.UserSchemes, s)
+			}
+		}
+
+		r.Projects = append(r.Projects, p)
+	}
+
+	if containerType == "workspace" {
+		for _, scheme := range result.ContainerToSchemes[containerPath] {
+			s := newReportScheme(scheme, containerPath, relPath)
+			if scheme.IsShared {
+				r.Container.SharedSchemes = append(r.Container.SharedSchemes, s)
+			} else {
+				r.Container.UserSchemes = append(r.Container.UserSchemes, s)
+			}
+		}
+	}
+
+	for _, missingProject := range missingProjects {
+		r.MissingProjects = append(r.MissingProjects, relPath(missingProject))
+	}
+	sort.Strings(r.MissingProjects)
+
+	filters, filterToTargets := groupFilteredTargets(result.FilteredTargets)
+	for _, filter := range filters {
+		f := reportTargetFilter{Filter: filter, Targets: []reportFilteredTarget{}}
+		for _, filtered := range filterToTargets[filter] {
+			f.Targets = append(f.Targets, reportFilteredTarget{
+				ID:      filtered.Target.ID,
+				Name:    filtered.Target.Name,
+				Project: relPath(filtered.ProjectPath),
+			})
+		}
+		r.FilteredTargets = append(r.FilteredTargets, f)
+	}
+
+	for _, warning := range result.Warnings {
+		r.Warnings = append(r.Warnings, relativeWarning(warning, containerPath))
+	}
+
+	return r
+}
+
+// relativeWarning replaces the paths in the container's directory, for example in the wrapped listing errors, with relative ones.
+func relativeWarning(warning, containerPath string) string {
+	dir := filepath.Dir(containerPath) + string(filepath.Separator)
+
+	var b strings.Builder
+	for {
+		i := strings.Index(warning, dir)
+		if i < 0 {
+			break
+		}
+		b.WriteString(warning[:i])
+		// Only whole paths are replaced, not the end of an other path (for example /private/tmp/src/ of the /tmp/src/ directory).
+		if i > 0 && !strings.ContainsRune(" (\"'=", rune(warning[i-1])) {
+			b.WriteString(dir)
+		}
+		warning = warning[i+len(dir):]
+	}
+	b.WriteString(warning)
+	return b.String()
+}
+
+func newReportScheme(scheme xcscheme.Scheme, schemeContainerPath string, relPath func(string) string) reportScheme {
+	reference := func(ref xcscheme.BuildableReference) reportReference {
+		project := ref.ReferencedContainer
+		if projectPath, err := ref.ReferencedContainerAbsPath(filepath.Dir(schemeContainerPath)); err == nil {
+			project = relPath(projectPath)
+		}
+		return reportReference{
+			ID:      ref.BlueprintIdentifier,
+			Name:    ref.BlueprintName,
+			Product: ref.BuildableName,
+			Project: project,
+		}
+	}
+	runnable := func(ref xcscheme.BuildableReference) *reportReference {
+		if ref.BlueprintIdentifier == "" {
+			return nil
+		}
+		r := reference(ref)
+		return &r
+	}
+
+	s := reportScheme{
+		Name: scheme.Name,
+		Actions: reportActions{
+			Build: reportBuildAction{Targets: []reportBuildTarget{}},
+			Test: reportTestAction{
+				Configuration: scheme.TestAction.BuildConfiguration,
+				Testables:     []reportTestable{},
+				TestPlans:     []string{},
+			},
+			Launch: reportRunAction{
+				Configuration: scheme.LaunchAction.BuildConfiguration,
+				Runnable:      runnable(scheme.LaunchAction.BuildableProductRunnable.BuildableReference),
+			},
+			Profile: reportRunAction{
+				Configuration: scheme.ProfileAction.BuildConfiguration,
+				Runnable:      runnable(scheme.ProfileAction.BuildableProductRunnable.BuildableReference),
+			},
+			Analyze: reportAction{Configuration: scheme.AnalyzeAction.BuildConfiguration},
+			Archive: reportAction{Configuration: scheme.ArchiveAction.BuildConfiguration},
+		},
+	}
+	if scheme.Path != "" {
+		s.Path = relPath(scheme.Path)
+	}
+
+	for _, entry := range scheme.BuildAction.BuildActionEntries {
+		target := reportBuildTarget{reportReference: reference(entry.BuildableReference), BuildFor: []string{}}
+		for _, action := range []struct{ name, value string }{
+			{"testing", entry.BuildForTesting},
+			{"running", entry.BuildForRunning},
+			{"profiling", entry.BuildForProfiling},
+			{"archiving", entry.BuildForArchiving},
+			{"analyzing", entry.BuildForAnalyzing},
+		} {
+			if action.value == yes {
+				target.BuildFor = append(target.BuildFor, action.name)
+			}
+		}
+		s.Actions.Build.Targets = append(s.Actions.Build.Targets, target)
+	}
+
+	for _, testable := range scheme.TestAction.Testables {
+		s.Actions.Test.Testables = append(s.Actions.Test.Testables, reportTestable{
+			reportReference: reference(testable.BuildableReference),
+			Skipped:         testable.Skipped == yes,
+		})
+	}
+
+	if scheme.TestAction.TestPlans != nil {
+		for _, testPlan := range scheme.TestAction.TestPlans.TestPlanReferences {
+			s.Actions.Test.TestPlans = append(s.Actions.Test.TestPlans, testPlan.Reference)
+		}
+	}
+
+	return s
+}
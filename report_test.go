@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+func TestNewReportGeneratedSchemes(t *testing.T) {
+	workspacePath := filepath.Join("/src", "App.xcworkspace")
+	appProject := filepath.Join("/src", "App.xcodeproj")
+	coreProject := filepath.Join("/src", "Core", "Core.xcodeproj")
+	appScheme := xcscheme.Scheme{Name: "App", Path: sharedSchemePath(appProject, "App"), IsShared: true}
+	coreAppScheme := xcscheme.Scheme{Name: "App", Path: sharedSchemePath(coreProject, "App"), IsShared: true}
+
+	// Only the scheme of the App project is generated, the committed scheme of the same name in Core is shared.
+	result := Result{
+		ContainerToSchemes: map[string][]xcscheme.Scheme{
+			appProject:  {appScheme},
+			coreProject: {coreAppScheme},
+		},
+		GeneratedSchemes:     []string{"App"},
+		GeneratedSchemePaths: []string{appScheme.Path},
+	}
+	r := newReport(workspacePath, []xcodeproject.XcodeProj{newTestProject(appProject), newTestProject(coreProject)}, nil, result)
+
+	if len(r.Projects) != 2 {
+		t.Fatalf("report has %d projects, want 2", len(r.Projects))
+	}
+	app, core := r.Projects[0], r.Projects[1]
+	if len(app.GeneratedSchemes) != 1 || len(app.SharedSchemes) != 0 {
+		t.Errorf("%s: generated = %v, shared = %v, want the App scheme generated", app.Path, app.GeneratedSchemes, app.SharedSchemes)
+	}
+	if len(core.GeneratedSchemes) != 0 || len(core.SharedSchemes) != 1 {
+		t.Errorf("%s: generated = %v, shared = %v, want the App scheme shared", core.Path, core.GeneratedSchemes, core.SharedSchemes)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	root := t.TempDir()
+	srcDir := filepath.Join(root, "src")
+	if err := copyDir(filepath.Join("testdata", "xcodebuild_list"), srcDir); err != nil {
+		t.Fatal(err)
+	}
+	// A project outside of the write root, a missing project and a malformed scheme, each of them is reported with a relative path.
+	if err := copyDir(filepath.Join("testdata", "xcodebuild_list", "Empty.xcodeproj"), filepath.Join(root, "Outside.xcodeproj")); err != nil {
+		t.Fatal(err)
+	}
+	workspacePath := filepath.Join(srcDir, "App.xcworkspace")
+	writeTestWorkspace(t, workspacePath, "group:App.xcodeproj", "group:Pods/Pods.xcodeproj", "group:../Outside.xcodeproj", "group:Missing/Missing.xcodeproj")
+	schemePath := sharedSchemePath(filepath.Join(srcDir, "App.xcodeproj"), "Broken")
+	if err := os.MkdirAll(filepath.Dir(schemePath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(schemePath, []byte("malformed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := openFileManifest(defaultManifestPath(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		ContainerPath:     workspacePath,
+		Mode:              generateMode,
+		DryRun:            true,
+		MalformedSchemes:  malformedSchemesSkip,
+		WriteRoot:         srcDir,
+		OutOfRootProjects: outOfRootProjectsSkip,
+		Manifest:          manifest,
+		Projects:          schemelist.NewProjectCache(),
+		DeployDir:         t.TempDir(),
+	}
+	result, err := (SchemeGenerator{}).run(cfg)
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if err := writeReport(cfg, result); err != nil {
+		t.Fatalf("writeReport() error = %v", err)
+	}
+
+	got := readTestFile(t, filepath.Join(cfg.DeployDir, reportFileName))
+	want := readTestFile(t, filepath.Join("testdata", reportFileName))
+	if got != want {
+		t.Errorf("report =\n%s\nwant\n%s", got, want)
+	}
+	if strings.Contains(got, root) {
+		t.Errorf("report contains the absolute path %s", root)
+	}
+}
+
+func TestRelativeWarning(t *testing.T) {
+	containerPath := filepath.Join("/tmp", "src", "App.xcworkspace")
+	tests := []struct {
+		warning string
+		want    string
+	}{
+		{warning: "failed to open /tmp/src/App.xcodeproj: EOF", want: "failed to open App.xcodeproj: EOF"},
+		{warning: "opening the Xcode project (/tmp/src/Pods/Pods.xcodeproj) failed", want: "opening the Xcode project (Pods/Pods.xcodeproj) failed"},
+		{warning: "/tmp/src/App.xcodeproj is malformed", want: "App.xcodeproj is malformed"},
+		{warning: "resolved to /private/tmp/src/App.xcodeproj", want: "resolved to /private/tmp/src/App.xcodeproj"},
+		{warning: "outside of /tmp/other/App.xcodeproj", want: "outside of /tmp/other/App.xcodeproj"},
+	}
+	for _, tt := range tests {
+		if got := relativeWarning(tt.warning, containerPath); got != tt.want {
+			t.Errorf("relativeWarning(%q) = %q, want %q", tt.warning, got, tt.want)
+		}
+	}
+}
+
+func TestReportFilteredTargets(t *testing.T) {
+	root := t.TempDir()
+	if err := copyDir(filepath.Join("testdata", "xcodebuild_list", "App.xcodeproj"), filepath.Join(root, "App.xcodeproj")); err != nil {
+		t.Fatal(err)
+	}
+	// Without shared and autocreated schemes, the schemes of the targets passing the filters are generated.
+	if err := os.RemoveAll(filepath.Join(root, "App.xcodeproj", "xcshareddata")); err != nil {
+		t.Fatal(err)
+	}
+	settingsDir := filepath.Join(root, "App.xcodeproj", "project.xcworkspace", "xcshareddata")
+	if err := os.MkdirAll(settingsDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	settings := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>IDEWorkspaceSharedSettings_AutocreateContextsIfNeeded</key>
+	<false/>
+</dict>
+</plist>
+`
+	if err := os.WriteFile(filepath.Join(settingsDir, "WorkspaceSettings.xcsettings"), []byte(settings), 0600); err != nil {
+		t.Fatal(err)
+	}
+	filter, err := parseTargetFilter("", "*Widget\nSampleKit")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{
+		ContainerPath:     filepath.Join(root, "App.xcodeproj"),
+		Mode:              generateMode,
+		DryRun:            true,
+		MalformedSchemes:  malformedSchemesSkip,
+		WriteRoot:         root,
+		OutOfRootProjects: outOfRootProjectsFail,
+		TargetFilter:      filter,
+		Projects:          schemelist.NewProjectCache(),
+		DeployDir:         t.TempDir(),
+	}
+	result, err := (SchemeGenerator{}).run(cfg)
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if err := writeReport(cfg, result); err != nil {
+		t.Fatalf("writeReport() error = %v", err)
+	}
+
+	var r struct {
+		FilteredTargets []struct {
+			Filter  string `json:"filter"`
+			Targets []struct {
+				Name    string `json:"name"`
+				Project string `json:"project"`
+			} `json:"targets"`
+		} `json:"filtered_targets"`
+	}
+	if err := json.Unmarshal([]byte(readTestFile(t, filepath.Join(cfg.DeployDir, reportFileName))), &r); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string][]string{}
+	for _, f := range r.FilteredTargets {
+		for _, target := range f.Targets {
+			got[f.Filter] = append(got[f.Filter], target.Name+" ("+target.Project+")")
+		}
+	}
+	want := map[string][]string{
+		"exclude_targets (*Widget)":   {"AppWidget (App.xcodeproj)"},
+		"exclude_targets (SampleKit)": {"SampleKit (App.xcodeproj)"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("filtered targets = %v, want %v", got, want)
+	}
+}
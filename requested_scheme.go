@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+	"golang.org/x/text/unicode/norm"
+)
+
+const maxSuggestions = 3
+
+var errTargetFiltered = errors.New("the scheme's target is filtered out")
+
+// findSharedScheme returns the shared scheme with the given name and its container path.
+func findSharedScheme(containerToSchemes map[string][]xcscheme.Scheme, name string) (xcscheme.Scheme, string, bool) {
+	for container, schemes := range containerToSchemes {
+		for _, scheme := range schemes {
+			if scheme.IsShared && schemelist.IsSameSchemeName(scheme.Name, name) {
+				return scheme, container, true
+			}
+		}
+	}
+	return xcscheme.Scheme{}, "", false
+}
+
+// recreateSchemes returns the default schemes of the project, ending the line ReCreateSchemes logs without a newline.
+func recreateSchemes(project xcodeproject.XcodeProj) []xcscheme.Scheme {
+	schemes := project.ReCreateSchemes()
+	fmt.Println()
+	return schemes
+}
+
+// generateRequestedScheme generates the named scheme and returns the project to save it in.
+func generateRequestedScheme(cfg Config, projects []xcodeproject.XcodeProj, name string) (generatedScheme, string, bool, error) {
+	if cfg.SchemeSpec != nil {
+		for _, entry := range cfg.SchemeSpec.Schemes {
+			if !schemelist.IsSameSchemeName(entry.Name, name) {
+				continue
+			}
+
+			projectToSchemes, err := schemeSpec{Schemes: []schemeSpecEntry{entry}}.render(projects, cfg.ContainerPath)
+			if err != nil {
+				return generatedScheme{}, "", false, err
+			}
+			for projectPath, schemes := range projectToSchemes {
+				return schemes[0], projectPath, true, nil
+			}
+		}
+
+		return generatedScheme{}, "", false, nil
+	}
+
+	for _, project := range projects {
+		for _, scheme := range recreateSchemes(project) {
+			if !schemelist.IsSameSchemeName(scheme.Name, name) {
+				continue
+			}
+
+			generated := newGeneratedScheme(scheme)
+			if _, removed := cfg.TargetFilter.filterSchemes(project, []generatedScheme{generated}); len(removed) > 0 {
+				return generatedScheme{}, "", false, fmt.Errorf("%w: target %s (%s) is removed by %s", errTargetFiltered, removed[0].Target.Name, filepath.Base(project.Path), removed[0].Filter)
+			}
+			return generated, project.Path, true, nil
+		}
+	}
+
+	return generatedScheme{}, "", false, nil
+}
+
+// schemeNotFoundError lists the target and scheme names closest to the requested scheme.
+type schemeNotFoundError struct {
+	Scheme         string
+	ClosestTargets []string
+	ClosestSchemes []string
+}
+
+func newSchemeNotFoundError(name string, projects []xcodeproject.XcodeProj, containerToSchemes map[string][]xcscheme.Scheme) schemeNotFoundError {
+	var targetNames []string
+	for _, project := range projects {
+		for _, target := range project.Proj.Targets {
+			if target.Type == xcodeproject.NativeTargetType && !target.IsTest() {
+				targetNames = append(targetNames, target.Name)
+			}
+		}
+	}
+
+	var schemeNames []string
+	for _, schemes := range containerToSchemes {
+		for _, scheme := range schemes {
+			schemeNames = append(schemeNames, scheme.Name)
+		}
+	}
+
+	return schemeNotFoundError{
+		Scheme:         name,
+		ClosestTargets: closestNames(name, targetNames),
+		ClosestSchemes: closestNames(name, schemeNames),
+	}
+}
+
+// Error implements the error interface
+func (e schemeNotFoundError) Error() string {
+	msg := fmt.Sprintf("scheme %s not found and no target matches it", e.Scheme)
+	if len(e.ClosestTargets) > 0 {
+		msg += fmt.Sprintf(", closest targets: %s", strings.Join(e.ClosestTargets, ", "))
+	}
+	if len(e.ClosestSchemes) > 0 {
+		msg += fmt.Sprintf(", closest schemes: %s", strings.Join(e.ClosestSchemes, ", "))
+	}
+	return msg
+}
+
+// closestNames returns the names with the smallest (case-insensitive) edit distance to the given name.
+func closestNames(name string, names []string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	seen := map[string]bool{}
+	var candidates []candidate
+	for _, n := range names {
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+
+		candidates = append(candidates, candidate{
+			name:     n,
+			distance: editDistance(strings.ToLower(norm.NFC.String(name)), strings.ToLower(norm.NFC.String(n))),
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance == candidates[j].distance {
+			return candidates[i].name < candidates[j].name
+		}
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	var closest []string
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		closest = append(closest, candidates[i].name)
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance of the given strings.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	previous := make([]int, len(t)+1)
+	current := make([]int, len(t)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		current[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, minInt(current[j-1]+1, previous[j-1]+cost))
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(t)]
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+func TestGenerateRequestedScheme(t *testing.T) {
+	project := newTestProject("/repo/App.xcodeproj",
+		newTestTarget("A1", "App", "com.apple.product-type.application"),
+		newTestTarget("A2", "AppWidget", "com.apple.product-type.app-extension"),
+	)
+
+	tests := []struct {
+		name        string
+		scheme      string
+		include     string
+		exclude     string
+		wantFound   bool
+		wantErrType error
+	}{
+		{name: "no filter", scheme: "AppWidget", wantFound: true},
+		{name: "not matching exclude", scheme: "AppWidget", exclude: "Pods-*", wantFound: true},
+		{name: "excluded target", scheme: "AppWidget", exclude: "*Widget", wantErrType: errTargetFiltered},
+		{name: "not included target", scheme: "AppWidget", include: "App", wantErrType: errTargetFiltered},
+		{name: "unknown target", scheme: "Missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := parseTargetFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("parseTargetFilter() error = %v", err)
+			}
+
+			scheme, projectPath, found, err := generateRequestedScheme(Config{TargetFilter: filter}, []xcodeproject.XcodeProj{project}, tt.scheme)
+			if tt.wantErrType != nil {
+				if !errors.Is(err, tt.wantErrType) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErrType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if found != tt.wantFound {
+				t.Fatalf("found = %v, want %v", found, tt.wantFound)
+			}
+			if found && (scheme.Name != tt.scheme || projectPath != project.Path) {
+				t.Errorf("generated %s in %s, want %s in %s", scheme.Name, projectPath, tt.scheme, project.Path)
+			}
+		})
+	}
+}
+
+func TestRunRequestedSchemeNotFoundBeforeWriting(t *testing.T) {
+	root := t.TempDir()
+	if err := copyDir(filepath.Join("testdata", "xcodebuild_list"), root); err != nil {
+		t.Fatal(err)
+	}
+	schemePath := sharedSchemePath(filepath.Join(root, "App.xcodeproj"), "App")
+	if err := os.MkdirAll(filepath.Dir(schemePath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(schemePath, []byte("malformed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := openFileManifest(defaultManifestPath(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		ContainerPath:     filepath.Join(root, "App.xcworkspace"),
+		Mode:              generateMode,
+		Scheme:            "Missing",
+		MalformedSchemes:  malformedSchemesRegenerate,
+		WriteRoot:         root,
+		OutOfRootProjects: outOfRootProjectsSkip,
+		Manifest:          manifest,
+		Projects:          schemelist.NewProjectCache(),
+	}
+	_, err = (SchemeGenerator{}).run(cfg)
+	var notFound schemeNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("run() error = %v, want schemeNotFoundError", err)
+	}
+
+	// The malformed scheme is not backed up and regenerated, as the step fails.
+	if content, err := os.ReadFile(schemePath); err != nil || string(content) != "malformed" {
+		t.Errorf("malformed scheme = %q, %v, want it untouched", content, err)
+	}
+	if _, err := os.Stat(schemePath + ".bak"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("malformed scheme is backed up: %v", err)
+	}
+}
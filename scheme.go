@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+)
+
+// generatedScheme extends the go-xcode Scheme model with the elements the step adds to the default schemes.
+type generatedScheme struct {
+	xcscheme.Scheme
+
+	BuildExecutionActions   schemeExecutionActions
+	TestExecutionActions    schemeExecutionActions
+	ArchiveExecutionActions schemeExecutionActions
+
+	EnvironmentVariables []environmentVariable
+}
+
+// newGeneratedScheme wraps the scheme, adding the default test action ReCreateSchemes leaves out for targets without tests.
+func newGeneratedScheme(scheme xcscheme.Scheme) generatedScheme {
+	if scheme.TestAction.BuildConfiguration == "" {
+		scheme.TestAction = defaultTestAction(scheme.LaunchAction.BuildConfiguration)
+	}
+	return generatedScheme{Scheme: scheme}
+}
+
+// legacySchemeLayoutVersion is the first LastUpgradeVersion leaving out the empty AdditionalOptions and the redundant test MacroExpansion.
+const legacySchemeLayoutVersion = 1100
+
+func isLegacySchemeLayout(lastUpgradeVersion string) bool {
+	version, err := strconv.Atoi(lastUpgradeVersion)
+	return err == nil && version < legacySchemeLayoutVersion
+}
+
+// buildableReference returns the reference of the scheme's main (first) build target.
+func (s generatedScheme) buildableReference() (xcscheme.BuildableReference, bool) {
+	if len(s.BuildAction.BuildActionEntries) == 0 {
+		return xcscheme.BuildableReference{}, false
+	}
+	return s.BuildAction.BuildActionEntries[0].BuildableReference, true
+}
+
+// Marshal returns the .xcscheme file contents of the scheme.
+func (s generatedScheme) Marshal() ([]byte, error) {
+	contents, err := xml.Marshal(s.toXML())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Scheme: %w", err)
+	}
+
+	doc, err := parseXMLDocument(contents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal Scheme: %w", err)
+	}
+	doc.Prolog = []xml.Token{xml.ProcInst{Target: "xml", Inst: []byte(`version="1.0" encoding="UTF-8"`)}}
+
+	return doc.Marshal()
+}
+
+func (s generatedScheme) toXML() schemeXML {
+	legacy := isLegacySchemeLayout(s.LastUpgradeVersion)
+
+	var macroExpansion *xcscheme.MacroExpansion
+	hasRunnable := s.LaunchAction.BuildableProductRunnable.BuildableReference.BlueprintIdentifier != ""
+	if s.TestAction.MacroExpansion.BuildableReference.BlueprintIdentifier != "" && (legacy || !hasRunnable) {
+		macroExpansion = &s.TestAction.MacroExpansion
+	}
+	var additionalOptions *xcscheme.AdditionalOptions
+	if legacy {
+		additionalOptions = &xcscheme.AdditionalOptions{}
+	}
+
+	return schemeXML{
+		LastUpgradeVersion: s.LastUpgradeVersion,
+		Version:            s.Version,
+		BuildAction: buildActionXML{
+			ParallelizeBuildables:     s.BuildAction.ParallelizeBuildables,
+			BuildImplicitDependencies: s.BuildAction.BuildImplicitDependencies,
+			PreActions:                newExecutionActionList(s.BuildExecutionActions.Pre),
+			PostActions:               newExecutionActionList(s.BuildExecutionActions.Post),
+			BuildActionEntries:        s.BuildAction.BuildActionEntries,
+		},
+		TestAction: testActionXML{
+			BuildConfiguration:           s.TestAction.BuildConfiguration,
+			SelectedDebuggerIdentifier:   s.TestAction.SelectedDebuggerIdentifier,
+			SelectedLauncherIdentifier:   s.TestAction.SelectedLauncherIdentifier,
+			ShouldUseLaunchSchemeArgsEnv: s.TestAction.ShouldUseLaunchSchemeArgsEnv,
+			ShouldAutocreateTestPlan:     s.TestAction.ShouldAutocreateTestPlan,
+			PreActions:                   newExecutionActionList(s.TestExecutionActions.Pre),
+			PostActions:                  newExecutionActionList(s.TestExecutionActions.Post),
+			TestPlans:                    s.TestAction.TestPlans,
+			Testables:                    &testablesXML{TestableReferences: s.TestAction.Testables},
+			MacroExpansion:               macroExpansion,
+			AdditionalOptions:            additionalOptions,
+		},
+		LaunchAction: launchActionXML{
+			BuildConfiguration:             s.LaunchAction.BuildConfiguration,
+			SelectedDebuggerIdentifier:     s.LaunchAction.SelectedDebuggerIdentifier,
+			SelectedLauncherIdentifier:     s.LaunchAction.SelectedLauncherIdentifier,
+			LaunchStyle:                    s.LaunchAction.LaunchStyle,
+			UseCustomWorkingDirectory:      s.LaunchAction.UseCustomWorkingDirectory,
+			IgnoresPersistentStateOnLaunch: s.LaunchAction.IgnoresPersistentStateOnLaunch,
+			DebugDocumentVersioning:        s.LaunchAction.DebugDocumentVersioning,
+			DebugServiceExtension:          s.LaunchAction.DebugServiceExtension,
+			AllowLocationSimulation:        s.LaunchAction.AllowLocationSimulation,
+			BuildableProductRunnable:       s.LaunchAction.BuildableProductRunnable,
+			EnvironmentVariables:           newEnvironmentVariableList(s.EnvironmentVariables),
+			AdditionalOptions:              additionalOptions,
+		},
+		ProfileAction: s.ProfileAction,
+		AnalyzeAction: s.AnalyzeAction,
+		ArchiveAction: archiveActionXML{
+			BuildConfiguration:       s.ArchiveAction.BuildConfiguration,
+			RevealArchiveInOrganizer: s.ArchiveAction.RevealArchiveInOrganizer,
+			PreActions:               newExecutionActionList(s.ArchiveExecutionActions.Pre),
+			PostActions:              newExecutionActionList(s.ArchiveExecutionActions.Post),
+		},
+	}
+}
+
+// schemeXML mirrors xcscheme.Scheme, with the elements missing from the go-xcode model.
+type schemeXML struct {
+	XMLName            xml.Name `xml:"Scheme"`
+	LastUpgradeVersion string   `xml:"LastUpgradeVersion,attr"`
+	Version            string   `xml:"version,attr"`
+
+	BuildAction   buildActionXML
+	TestAction    testActionXML
+	LaunchAction  launchActionXML
+	ProfileAction xcscheme.ProfileAction
+	AnalyzeAction xcscheme.AnalyzeAction
+	ArchiveAction archiveActionXML
+}
+
+type buildActionXML struct {
+	ParallelizeBuildables     string                      `xml:"parallelizeBuildables,attr"`
+	BuildImplicitDependencies string                      `xml:"buildImplicitDependencies,attr"`
+	PreActions                *executionActionList        `xml:"PreActions,omitempty"`
+	PostActions               *executionActionList        `xml:"PostActions,omitempty"`
+	BuildActionEntries        []xcscheme.BuildActionEntry `xml:"BuildActionEntries>BuildActionEntry"`
+}
+
+type testActionXML struct {
+	BuildConfiguration           string `xml:"buildConfiguration,attr"`
+	SelectedDebuggerIdentifier   string `xml:"selectedDebuggerIdentifier,attr"`
+	SelectedLauncherIdentifier   string `xml:"selectedLauncherIdentifier,attr"`
+	ShouldUseLaunchSchemeArgsEnv string `xml:"shouldUseLaunchSchemeArgsEnv,attr"`
+	ShouldAutocreateTestPlan     string `xml:"shouldAutocreateTestPlan,attr,omitempty"`
+
+	PreActions        *executionActionList `xml:"PreActions,omitempty"`
+	PostActions       *executionActionList `xml:"PostActions,omitempty"`
+	TestPlans         *xcscheme.TestPlans
+	Testables         *testablesXML
+	MacroExpansion    *xcscheme.MacroExpansion    `xml:"MacroExpansion,omitempty"`
+	AdditionalOptions *xcscheme.AdditionalOptions `xml:"AdditionalOptions,omitempty"`
+}
+
+// testablesXML is written even if empty, as Xcode does.
+type testablesXML struct {
+	TestableReferences []xcscheme.TestableReference `xml:"TestableReference"`
+}
+
+type launchActionXML struct {
+	BuildConfiguration             string `xml:"buildConfiguration,attr"`
+	SelectedDebuggerIdentifier     string `xml:"selectedDebuggerIdentifier,attr"`
+	SelectedLauncherIdentifier     string `xml:"selectedLauncherIdentifier,attr"`
+	LaunchStyle                    string `xml:"launchStyle,attr"`
+	UseCustomWorkingDirectory      string `xml:"useCustomWorkingDirectory,attr"`
+	IgnoresPersistentStateOnLaunch string `xml:"ignoresPersistentStateOnLaunch,attr"`
+	DebugDocumentVersioning        string `xml:"debugDocumentVersioning,attr"`
+	DebugServiceExtension          string `xml:"debugServiceExtension,attr"`
+	AllowLocationSimulation        string `xml:"allowLocationSimulation,attr"`
+
+	BuildableProductRunnable xcscheme.BuildableProductRunnable
+	EnvironmentVariables     *environmentVariableList    `xml:"EnvironmentVariables,omitempty"`
+	AdditionalOptions        *xcscheme.AdditionalOptions `xml:"AdditionalOptions,omitempty"`
+}
+
+type archiveActionXML struct {
+	BuildConfiguration       string               `xml:"buildConfiguration,attr"`
+	RevealArchiveInOrganizer string               `xml:"revealArchiveInOrganizer,attr"`
+	PreActions               *executionActionList `xml:"PreActions,omitempty"`
+	PostActions              *executionActionList `xml:"PostActions,omitempty"`
+}
+
+// executionActionList is the PreActions or PostActions element of a scheme action.
+type executionActionList struct {
+	ExecutionActions []executionAction `xml:"ExecutionAction"`
+}
+
+func newExecutionActionList(actions []executionAction) *executionActionList {
+	if len(actions) == 0 {
+		return nil
+	}
+	return &executionActionList{ExecutionActions: actions}
+}
+
+// environmentVariable is an environment variable set by the launch action (and the test action using the launch action's arguments).
+type environmentVariable struct {
+	Key       string `xml:"key,attr"`
+	Value     string `xml:"value,attr"`
+	IsEnabled string `xml:"isEnabled,attr"`
+}
+
+type environmentVariableList struct {
+	EnvironmentVariables []environmentVariable `xml:"EnvironmentVariable"`
+}
+
+func newEnvironmentVariableList(variables []environmentVariable) *environmentVariableList {
+	if len(variables) == 0 {
+		return nil
+	}
+	return &environmentVariableList{EnvironmentVariables: variables}
+}
+
+// sharedSchemePath returns the path of the named shared Scheme in the given project.
+func sharedSchemePath(projectPath, name string) string {
+	return filepath.Join(projectPath, "xcshareddata", "xcschemes", name+".xcscheme")
+}
+
+// saveSharedScheme saves the shared scheme at the path xcodeproj.XcodeProj.SaveSharedScheme uses.
+func saveSharedScheme(projectPath string, scheme generatedScheme, writeRoot string, manifest *fileManifest) error {
+	return saveSharedSchemes([]string{projectPath}, map[string][]generatedScheme{projectPath: {scheme}}, writeRoot, manifest)
+}
+
+// saveSharedSchemes saves the shared schemes of the projects inside the write root, all or nothing, recording them in the manifest.
+func saveSharedSchemes(projectPaths []string, projectToSchemes map[string][]generatedScheme, writeRoot string, manifest *fileManifest) error {
+	transaction := schemeTransaction{writeRoot: writeRoot, manifest: manifest}
+	for _, projectPath := range projectPaths {
+		for _, scheme := range projectToSchemes[projectPath] {
+			if err := transaction.stage(projectPath, scheme); err != nil {
+				return transaction.rollbackAfter(fmt.Errorf("saving scheme %s failed: %w", scheme.Name, err))
+			}
+		}
+	}
+	return transaction.commit()
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+)
+
+// The scheme attribute values Xcode uses, when creating a new scheme (see xcodeproj.XcodeProj.ReCreateSchemes).
+const (
+	yes                         = "YES"
+	no                          = "NO"
+	buildableID                 = "primary"
+	defaultDebugConfiguration   = "Debug"
+	defaultReleaseConfiguration = "Release"
+	debuggerID                  = "Xcode.DebuggerFoundation.Debugger.LLDB"
+	launcherID                  = "Xcode.DebuggerFoundation.Launcher.LLDB"
+	lastUpgradeVersion          = "1240"
+	schemeVersion               = "1.3"
+)
+
+func hasConfiguration(configurationList xcodeproject.ConfigurationList, name string) bool {
+	for _, configuration := range configurationList.BuildConfigurations {
+		if configuration.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func debugConfigurationName(target xcodeproject.Target) string {
+	if hasConfiguration(target.BuildConfigurationList, defaultDebugConfiguration) {
+		return defaultDebugConfiguration
+	}
+	return target.BuildConfigurationList.DefaultConfigurationName
+}
+
+func releaseConfigurationName(target xcodeproject.Target) string {
+	if hasConfiguration(target.BuildConfigurationList, defaultReleaseConfiguration) {
+		return defaultReleaseConfiguration
+	}
+	return target.BuildConfigurationList.DefaultConfigurationName
+}
+
+func newBuildableReference(target xcodeproject.Target, projectName string) xcscheme.BuildableReference {
+	return xcscheme.BuildableReference{
+		BuildableIdentifier: buildableID,
+		BlueprintIdentifier: target.ID,
+		BuildableName:       path.Base(target.ProductReference.Path),
+		BlueprintName:       target.Name,
+		ReferencedContainer: fmt.Sprintf("container:%s", projectName),
+	}
+}
+
+func newBuildAction(targets []xcodeproject.Target, projectName string) xcscheme.BuildAction {
+	buildAction := xcscheme.BuildAction{
+		ParallelizeBuildables:     yes,
+		BuildImplicitDependencies: yes,
+	}
+
+	for _, target := range targets {
+		buildAction.BuildActionEntries = append(buildAction.BuildActionEntries, xcscheme.BuildActionEntry{
+			BuildForTesting:    yes,
+			BuildForRunning:    yes,
+			BuildForProfiling:  yes,
+			BuildForArchiving:  yes,
+			BuildForAnalyzing:  yes,
+			BuildableReference: newBuildableReference(target, projectName),
+		})
+	}
+
+	return buildAction
+}
+
+// defaultTestAction is the test action of a new scheme, before any test is added to it.
+func defaultTestAction(configuration string) xcscheme.TestAction {
+	return xcscheme.TestAction{
+		BuildConfiguration:           configuration,
+		SelectedDebuggerIdentifier:   debuggerID,
+		SelectedLauncherIdentifier:   launcherID,
+		ShouldUseLaunchSchemeArgsEnv: yes,
+		Testables:                    []xcscheme.TestableReference{},
+	}
+}
+
+func newTestAction(buildTarget xcodeproject.Target, testTargets []xcodeproject.Target, testPlans []string, configuration, projectName string) xcscheme.TestAction {
+	testAction := defaultTestAction(configuration)
+	if len(testTargets) == 0 && len(testPlans) == 0 {
+		return testAction
+	}
+
+	testAction.MacroExpansion = xcscheme.MacroExpansion{
+		BuildableReference: newBuildableReference(buildTarget, projectName),
+	}
+
+	for _, testTarget := range testTargets {
+		testAction.Testables = append(testAction.Testables, xcscheme.TestableReference{
+			Skipped:            no,
+			BuildableReference: newBuildableReference(testTarget, projectName),
+		})
+	}
+
+	if len(testPlans) > 0 {
+		testAction.TestPlans = &xcscheme.TestPlans{}
+		for i, testPlan := range testPlans {
+			reference := xcscheme.TestPlanReference{Reference: "container:" + filepath.ToSlash(testPlan)}
+			if i == 0 {
+				reference.Default = yes
+			}
+			testAction.TestPlans.TestPlanReferences = append(testAction.TestPlans.TestPlanReferences, reference)
+		}
+	}
+
+	return testAction
+}
+
+func newBuildableProductRunnable(target xcodeproject.Target, projectName string) xcscheme.BuildableProductRunnable {
+	return xcscheme.BuildableProductRunnable{
+		RunnableDebuggingMode: "0",
+		BuildableReference:    newBuildableReference(target, projectName),
+	}
+}
+
+func newLaunchAction(target xcodeproject.Target, configuration, projectName string) xcscheme.LaunchAction {
+	return xcscheme.LaunchAction{
+		BuildConfiguration:             configuration,
+		SelectedDebuggerIdentifier:     debuggerID,
+		SelectedLauncherIdentifier:     launcherID,
+		LaunchStyle:                    "0",
+		UseCustomWorkingDirectory:      no,
+		IgnoresPersistentStateOnLaunch: no,
+		DebugDocumentVersioning:        yes,
+		DebugServiceExtension:          "internal",
+		AllowLocationSimulation:        yes,
+		BuildableProductRunnable:       newBuildableProductRunnable(target, projectName),
+	}
+}
+
+func newProfileAction(target xcodeproject.Target, configuration, projectName string) xcscheme.ProfileAction {
+	return xcscheme.ProfileAction{
+		BuildConfiguration:           configuration,
+		ShouldUseLaunchSchemeArgsEnv: yes,
+		UseCustomWorkingDirectory:    no,
+		DebugDocumentVersioning:      yes,
+		BuildableProductRunnable:     newBuildableProductRunnable(target, projectName),
+	}
+}
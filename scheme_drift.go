@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+const schemeDriftFileName = "scheme_drift.diff"
+
+// schemeDrift is the difference between a committed shared scheme and the generated scheme of its main build target.
+type schemeDrift struct {
+	SchemePath string
+	Diff       string
+}
+
+// diff compares the committed shared schemes with the schemes ReCreateSchemes would generate for the same targets.
+func (g SchemeGenerator) diff(cfg Config, container schemelist.Container, containerToSchemes map[string][]xcscheme.Scheme) error {
+	fmt.Println()
+	log.Infof("Comparing shared Schemes with the generated ones...")
+
+	projects, missingProjects, err := container.Projects()
+	if err != nil {
+		return fmt.Errorf("getting projects failed: %w", err)
+	}
+
+	for _, missingProject := range missingProjects {
+		log.Warnf("Skipping project (%s), as it is not present", pathRelativeToWorkspace(missingProject, cfg.ContainerPath))
+	}
+
+	recreatedSchemes := map[string][]xcscheme.Scheme{}
+	var drifts []schemeDrift
+	var compared int
+	for _, containerPath := range sortedContainerPaths(containerToSchemes) {
+		for _, scheme := range containerToSchemes[containerPath] {
+			if !scheme.IsShared || scheme.Path == "" {
+				// Skip the default schemes Xcode would autocreate, they are not committed.
+				continue
+			}
+			compared++
+
+			schemePath := pathRelativeToWorkspace(scheme.Path, cfg.ContainerPath)
+			generated, err := generatedSchemeForTarget(scheme, containerPath, projects, recreatedSchemes)
+			if err != nil {
+				log.Warnf("%s: %s", schemePath, err)
+			}
+
+			var generatedSummary []string
+			if generated != nil {
+				generatedSummary = schemeSummary(*generated)
+			}
+
+			diff := unifiedDiff("a/"+schemePath, "b/"+schemePath+" (generated)", schemeSummary(scheme), generatedSummary)
+			if diff != "" {
+				drifts = append(drifts, schemeDrift{SchemePath: schemePath, Diff: diff})
+			}
+		}
+	}
+
+	for _, drift := range drifts {
+		fmt.Println()
+		log.Warnf("%s differs from the generated Scheme:", drift.SchemePath)
+		fmt.Print(drift.Diff)
+	}
+
+	if len(drifts) > 0 && cfg.DeployDir != "" {
+		pth := filepath.Join(cfg.DeployDir, schemeDriftFileName)
+		var contents string
+		for _, drift := range drifts {
+			contents += drift.Diff
+		}
+		if err := os.WriteFile(pth, []byte(contents), 0600); err != nil {
+			return fmt.Errorf("saving scheme drift failed: %w", err)
+		}
+
+		fmt.Println()
+		log.Printf("Scheme drift saved to: %s", pth)
+	}
+
+	fmt.Println()
+	if len(drifts) == 0 {
+		log.Donef("%d shared Scheme(s) match the generated Schemes.", compared)
+		return nil
+	}
+
+	if cfg.DiffFailThreshold != nil && len(drifts) > *cfg.DiffFailThreshold {
+		return fmt.Errorf("%d of %d shared Scheme(s) differ from the generated Schemes, more than the allowed %d", len(drifts), compared, *cfg.DiffFailThreshold)
+	}
+
+	log.Warnf("%d of %d shared Scheme(s) differ from the generated Schemes.", len(drifts), compared)
+	return nil
+}
+
+// generatedSchemeForTarget returns the generated scheme of the scheme's main build target, recreatedSchemes caches them by project.
+func generatedSchemeForTarget(scheme xcscheme.Scheme, containerPath string, projects []xcodeproject.XcodeProj, recreatedSchemes map[string][]xcscheme.Scheme) (*xcscheme.Scheme, error) {
+	entry, ok := scheme.AppBuildActionEntry()
+	if !ok {
+		if len(scheme.BuildAction.BuildActionEntries) == 0 {
+			return nil, fmt.Errorf("scheme has no build targets")
+		}
+		entry = scheme.BuildAction.BuildActionEntries[0]
+	}
+	reference := entry.BuildableReference
+
+	projectPath, err := reference.ReferencedContainerAbsPath(filepath.Dir(containerPath))
+	if err != nil {
+		return nil, fmt.Errorf("resolving the project of target %s failed: %w", reference.BlueprintName, err)
+	}
+
+	for _, project := range projects {
+		if project.Path != projectPath {
+			continue
+		}
+
+		target, ok := project.Proj.Target(reference.BlueprintIdentifier)
+		if !ok {
+			if target, ok = project.Proj.TargetByName(reference.BlueprintName); !ok {
+				return nil, fmt.Errorf("target %s not found in %s", reference.BlueprintName, filepath.Base(projectPath))
+			}
+		}
+
+		schemes, ok := recreatedSchemes[project.Path]
+		if !ok {
+			schemes = recreateSchemes(project)
+			recreatedSchemes[project.Path] = schemes
+		}
+
+		for _, scheme := range schemes {
+			// Compared as written, with the default test action of the schemes without tests.
+			generated := newGeneratedScheme(scheme)
+			if generatedReference, ok := generated.buildableReference(); ok && generatedReference.BlueprintIdentifier == target.ID {
+				return &generated.Scheme, nil
+			}
+		}
+
+		return nil, fmt.Errorf("no default scheme is generated for target %s", target.Name)
+	}
+
+	return nil, fmt.Errorf("project %s is not part of the container", filepath.Base(projectPath))
+}
+
+// schemeSummary describes the targets, testables, configurations and runnables of a scheme, line by line.
+func schemeSummary(scheme xcscheme.Scheme) []string {
+	lines := []string{"Build targets:"}
+	for _, entry := range scheme.BuildAction.BuildActionEntries {
+		var actions []string
+		for _, action := range []struct{ name, value string }{
+			{"testing", entry.BuildForTesting},
+			{"running", entry.BuildForRunning},
+			{"profiling", entry.BuildForProfiling},
+			{"archiving", entry.BuildForArchiving},
+			{"analyzing", entry.BuildForAnalyzing},
+		} {
+			if action.value == yes {
+				actions = append(actions, action.name)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("  %s, built for: %s", referenceDescription(entry.BuildableReference), strings.Join(actions, ", ")))
+	}
+
+	lines = append(lines, "Testables:")
+	for _, testable := range scheme.TestAction.Testables {
+		line := "  " + referenceDescription(testable.BuildableReference)
+		if testable.Skipped == yes {
+			line += ", skipped"
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines,
+		"Configurations:",
+		"  test: "+scheme.TestAction.BuildConfiguration,
+		"  launch: "+scheme.LaunchAction.BuildConfiguration,
+		"  profile: "+scheme.ProfileAction.BuildConfiguration,
+		"  analyze: "+scheme.AnalyzeAction.BuildConfiguration,
+		"  archive: "+scheme.ArchiveAction.BuildConfiguration,
+		"Runnables:",
+		"  launch: "+referenceDescription(scheme.LaunchAction.BuildableProductRunnable.BuildableReference),
+		"  profile: "+referenceDescription(scheme.ProfileAction.BuildableProductRunnable.BuildableReference),
+	)
+
+	return lines
+}
+
+func referenceDescription(reference xcscheme.BuildableReference) string {
+	if reference.BlueprintIdentifier == "" {
+		return "-"
+	}
+
+	// The container is relative to the scheme's container, only its name is compared.
+	_, container, _ := strings.Cut(reference.ReferencedContainer, ":")
+	return fmt.Sprintf("%s (%s, %s)", reference.BlueprintName, reference.BuildableName, filepath.Base(container))
+}
+
+func sortedContainerPaths(containerToSchemes map[string][]xcscheme.Scheme) []string {
+	var containerPaths []string
+	for containerPath := range containerToSchemes {
+		containerPaths = append(containerPaths, containerPath)
+	}
+	sort.Strings(containerPaths)
+	return containerPaths
+}
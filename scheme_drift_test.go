@@ -0,0 +1,31 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+)
+
+// A freshly generated scheme does not drift: the schemes are compared as they are written.
+func TestGeneratedSchemeForTarget(t *testing.T) {
+	project, err := xcodeproject.Open(filepath.Join("testdata", "xcodebuild_list", "App.xcodeproj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	projects := []xcodeproject.XcodeProj{project}
+
+	for _, recreated := range project.ReCreateSchemes() {
+		written := newGeneratedScheme(recreated).Scheme
+		t.Run(written.Name, func(t *testing.T) {
+			generated, err := generatedSchemeForTarget(written, project.Path, projects, map[string][]xcscheme.Scheme{})
+			if err != nil {
+				t.Fatalf("generatedSchemeForTarget() error = %v", err)
+			}
+			if diff := unifiedDiff("written", "generated", schemeSummary(written), schemeSummary(*generated)); diff != "" {
+				t.Errorf("the written scheme drifts from the generated one:\n%s", diff)
+			}
+		})
+	}
+}
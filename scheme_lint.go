@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+// schemeIssue is a broken reference in a shared scheme.
+type schemeIssue struct {
+	SchemePath string
+	// Element is the path of the scheme element with the broken reference, for example 'TestAction/Testables/TestableReference[1]'.
+	Element string
+	Reason  string
+}
+
+// schemeReference is a BuildableReference of a scheme, with the path of the element it belongs to.
+type schemeReference struct {
+	element   string
+	reference xcscheme.BuildableReference
+}
+
+// lintSchemes reports and returns the broken references of the committed shared schemes, and fails if configured so.
+func (g SchemeGenerator) lintSchemes(cfg Config, container schemelist.Container, containerToSchemes map[string][]xcscheme.Scheme) ([]schemeIssue, error) {
+	hasCommittedSchemes := false
+	for _, schemes := range containerToSchemes {
+		for _, scheme := range schemes {
+			if scheme.IsShared && scheme.Path != "" {
+				hasCommittedSchemes = true
+			}
+		}
+	}
+	if !hasCommittedSchemes {
+		return nil, nil
+	}
+
+	projects, _, err := container.Projects()
+	if err != nil {
+		log.Warnf("Failed to open projects, checking Scheme references without them: %s", err)
+	}
+
+	issues := newSchemeLinter(projects).lint(containerToSchemes)
+	if len(issues) == 0 {
+		return nil, nil
+	}
+
+	printSchemeIssues(issues, cfg.ContainerPath)
+
+	if cfg.FailOnBrokenSchemes {
+		return nil, fmt.Errorf("%d broken reference(s) found in shared schemes", len(issues))
+	}
+	return issues, nil
+}
+
+// schemeLinter checks the shared schemes for references to missing projects, targets and configurations.
+type schemeLinter struct {
+	// projects caches the opened projects by path, nil values mark projects which failed to open.
+	projects map[string]*xcodeproject.XcodeProj
+}
+
+func newSchemeLinter(projects []xcodeproject.XcodeProj) schemeLinter {
+	linter := schemeLinter{projects: map[string]*xcodeproject.XcodeProj{}}
+	for i := range projects {
+		linter.projects[projects[i].Path] = &projects[i]
+	}
+	return linter
+}
+
+// lint returns the issues of the committed shared schemes.
+func (l schemeLinter) lint(containerToSchemes map[string][]xcscheme.Scheme) []schemeIssue {
+	var issues []schemeIssue
+	for _, containerPath := range sortedContainerPaths(containerToSchemes) {
+		for _, scheme := range containerToSchemes[containerPath] {
+			if !scheme.IsShared || scheme.Path == "" {
+				continue
+			}
+			issues = append(issues, l.lintScheme(scheme, containerPath)...)
+		}
+	}
+	return issues
+}
+
+func (l schemeLinter) lintScheme(scheme xcscheme.Scheme, containerPath string) []schemeIssue {
+	var issues []schemeIssue
+	addIssue := func(element, reason string) {
+		issues = append(issues, schemeIssue{SchemePath: scheme.Path, Element: element, Reason: reason})
+	}
+
+	var referencedProjects []*xcodeproject.XcodeProj
+	for _, ref := range schemeReferences(scheme) {
+		projectPath, err := ref.reference.ReferencedContainerAbsPath(filepath.Dir(containerPath))
+		if err != nil {
+			addIssue(ref.element, err.Error())
+			continue
+		}
+
+		project, err := l.project(projectPath)
+		if err != nil {
+			addIssue(ref.element, fmt.Sprintf("referenced container (%s): %s", ref.reference.ReferencedContainer, err))
+			continue
+		}
+		referencedProjects = append(referencedProjects, project)
+
+		if _, ok := project.Proj.Target(ref.reference.BlueprintIdentifier); !ok {
+			addIssue(ref.element, fmt.Sprintf("target %s (%s) not found in %s", ref.reference.BlueprintName, ref.reference.BlueprintIdentifier, filepath.Base(project.Path)))
+		}
+	}
+
+	if len(referencedProjects) == 0 {
+		return issues
+	}
+
+	for _, action := range []struct{ element, configuration string }{
+		{"TestAction", scheme.TestAction.BuildConfiguration},
+		{"LaunchAction", scheme.LaunchAction.BuildConfiguration},
+		{"ProfileAction", scheme.ProfileAction.BuildConfiguration},
+		{"AnalyzeAction", scheme.AnalyzeAction.BuildConfiguration},
+		{"ArchiveAction", scheme.ArchiveAction.BuildConfiguration},
+	} {
+		if action.configuration == "" {
+			continue
+		}
+
+		found := false
+		for _, project := range referencedProjects {
+			if hasConfiguration(project.Proj.BuildConfigurationList, action.configuration) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			addIssue(action.element, fmt.Sprintf("build configuration %s not found in the referenced projects", action.configuration))
+		}
+	}
+
+	return issues
+}
+
+func (l schemeLinter) project(pth string) (*xcodeproject.XcodeProj, error) {
+	if project, ok := l.projects[pth]; ok {
+		if project == nil {
+			return nil, fmt.Errorf("failed to open project")
+		}
+		return project, nil
+	}
+
+	if exist, err := pathutil.IsPathExists(pth); err != nil {
+		return nil, err
+	} else if !exist {
+		return nil, fmt.Errorf("project does not exist")
+	}
+
+	project, err := xcodeproject.Open(pth)
+	if err != nil {
+		l.projects[pth] = nil
+		return nil, fmt.Errorf("failed to open project: %w", err)
+	}
+
+	l.projects[pth] = &project
+	return &project, nil
+}
+
+// schemeReferences returns the BuildableReferences of the scheme's actions.
+func schemeReferences(scheme xcscheme.Scheme) []schemeReference {
+	var references []schemeReference
+	add := func(element string, reference xcscheme.BuildableReference) {
+		if reference.BlueprintIdentifier == "" && reference.ReferencedContainer == "" {
+			return
+		}
+		references = append(references, schemeReference{element: element, reference: reference})
+	}
+
+	for i, entry := range scheme.BuildAction.BuildActionEntries {
+		add(fmt.Sprintf("BuildAction/BuildActionEntries/BuildActionEntry[%d]", i+1), entry.BuildableReference)
+	}
+	for i, testable := range scheme.TestAction.Testables {
+		add(fmt.Sprintf("TestAction/Testables/TestableReference[%d]", i+1), testable.BuildableReference)
+	}
+	add("TestAction/MacroExpansion", scheme.TestAction.MacroExpansion.BuildableReference)
+	add("LaunchAction/BuildableProductRunnable", scheme.LaunchAction.BuildableProductRunnable.BuildableReference)
+	add("ProfileAction/BuildableProductRunnable", scheme.ProfileAction.BuildableProductRunnable.BuildableReference)
+
+	return references
+}
+
+func printSchemeIssues(issues []schemeIssue, containerPath string) {
+	fmt.Println()
+	log.Warnf("Broken references in shared Schemes:")
+
+	var lastSchemePath string
+	for _, issue := range issues {
+		if issue.SchemePath != lastSchemePath {
+			log.Warnf("- %s", pathRelativeToWorkspace(issue.SchemePath, containerPath))
+			lastSchemePath = issue.SchemePath
+		}
+		log.Warnf("  - %s: %s", issue.Element, issue.Reason)
+	}
+}
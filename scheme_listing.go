@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+const (
+	malformedSchemesFail       = "fail"
+	malformedSchemesSkip       = "skip"
+	malformedSchemesRegenerate = "backup_and_regenerate"
+)
+
+// skippedSchemeConflict returns the malformed scheme file a generated scheme would overwrite, if it is left untouched by the skip policy.
+func skippedSchemeConflict(skipped []schemelist.MalformedScheme, projectPath, name string) (schemelist.MalformedScheme, bool) {
+	dir := filepath.Join(projectPath, "xcshareddata", "xcschemes")
+	for _, scheme := range skipped {
+		if filepath.Dir(scheme.Path) == dir && schemelist.IsSameSchemeName(scheme.Name(), name) {
+			return scheme, true
+		}
+	}
+	return schemelist.MalformedScheme{}, false
+}
+
+// withoutSkippedSchemes removes the generated schemes which would overwrite a malformed scheme file left untouched by the skip policy.
+func withoutSkippedSchemes(projectToSchemes map[string][]generatedScheme, skipped []schemelist.MalformedScheme, containerPath string) map[string][]generatedScheme {
+	if len(skipped) == 0 {
+		return projectToSchemes
+	}
+
+	printed := false
+	for projectPath, schemes := range projectToSchemes {
+		var kept []generatedScheme
+		for _, scheme := range schemes {
+			if conflict, ok := skippedSchemeConflict(skipped, projectPath, scheme.Name); ok {
+				if !printed {
+					fmt.Println()
+					printed = true
+				}
+				log.Warnf("Not generating Scheme %s, it would overwrite the malformed Scheme file %s", scheme.Name, pathRelativeToWorkspace(conflict.Path, containerPath))
+				continue
+			}
+			kept = append(kept, scheme)
+		}
+		projectToSchemes[projectPath] = kept
+	}
+	return projectToSchemes
+}
+
+// schemeRegeneration is a malformed scheme file to back up, and the scheme generated in its place if its target is found.
+type schemeRegeneration struct {
+	Scheme      schemelist.MalformedScheme
+	BackupPath  string
+	Generated   generatedScheme
+	ProjectPath string
+	Found       bool
+}
+
+// planMalformedSchemes reports the malformed scheme files and returns the ones to regenerate, without writing.
+func (g SchemeGenerator) planMalformedSchemes(cfg Config, container schemelist.Container, malformed []schemelist.MalformedScheme) ([]schemeRegeneration, error) {
+	fmt.Println()
+	log.Warnf("Malformed Scheme files:")
+	for _, scheme := range malformed {
+		log.Warnf("- %s: %s", pathRelativeToWorkspace(scheme.Path, cfg.ContainerPath), scheme.Err)
+	}
+
+	switch cfg.MalformedSchemes {
+	case malformedSchemesFail:
+		return nil, fmt.Errorf("%d Scheme file(s) can not be parsed", len(malformed))
+	case malformedSchemesSkip:
+		log.Warnf("Skipping the malformed Scheme file(s), they are not counted as shared Schemes, and no Scheme is generated in their place.")
+		return nil, nil
+	}
+
+	projects, _, err := container.Projects()
+	if err != nil {
+		return nil, fmt.Errorf("getting projects failed: %w", err)
+	}
+
+	fmt.Println()
+	log.Infof("Backing up and regenerating the malformed Schemes...")
+
+	var regenerations []schemeRegeneration
+	for _, scheme := range malformed {
+		backupPath, err := schemeBackupPath(scheme.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		generated, projectPath, found, err := generateRequestedScheme(cfg, projects, scheme.Name())
+		if errors.Is(err, errTargetFiltered) {
+			log.Warnf("- %s: left untouched, %s", pathRelativeToWorkspace(scheme.Path, cfg.ContainerPath), err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := checkWritePath(cfg.WriteRoot, scheme.Path); err != nil {
+			log.Warnf("- %s: left untouched, %s", pathRelativeToWorkspace(scheme.Path, cfg.ContainerPath), err)
+			continue
+		}
+
+		regenerations = append(regenerations, schemeRegeneration{
+			Scheme:      scheme,
+			BackupPath:  backupPath,
+			Generated:   addExecutionScripts(generated, cfg.ExecutionScripts),
+			ProjectPath: projectPath,
+			Found:       found,
+		})
+	}
+
+	return regenerations, nil
+}
+
+// regenerateMalformedSchemes backs up the planned scheme files and saves their regenerated schemes, returning their names and paths.
+func (g SchemeGenerator) regenerateMalformedSchemes(cfg Config, regenerations []schemeRegeneration) ([]string, []string, error) {
+	var regenerated, regeneratedPaths []string
+	for _, r := range regenerations {
+		if cfg.DryRun {
+			log.Printf("- %s: would be moved to %s", pathRelativeToWorkspace(r.Scheme.Path, cfg.ContainerPath), filepath.Base(r.BackupPath))
+			if r.Found {
+				log.Printf("  Scheme %s would be generated in %s", r.Generated.Name, pathRelativeToWorkspace(r.ProjectPath, cfg.ContainerPath))
+			}
+			continue
+		}
+
+		if err := cfg.Manifest.recordFile(r.Scheme.Path); err != nil {
+			return nil, nil, fmt.Errorf("failed to record %s in the manifest: %w", r.Scheme.Path, err)
+		}
+		if err := cfg.Manifest.recordFile(r.BackupPath); err != nil {
+			return nil, nil, fmt.Errorf("failed to record %s in the manifest: %w", r.BackupPath, err)
+		}
+		if err := os.Rename(r.Scheme.Path, r.BackupPath); err != nil {
+			return nil, nil, fmt.Errorf("backing up scheme %s failed: %w", r.Scheme.Path, err)
+		}
+		log.Printf("- %s: moved to %s", pathRelativeToWorkspace(r.Scheme.Path, cfg.ContainerPath), filepath.Base(r.BackupPath))
+
+		if !r.Found {
+			log.Warnf("  No target found to regenerate Scheme %s", r.Scheme.Name())
+			continue
+		}
+
+		if err := saveSharedScheme(r.ProjectPath, r.Generated, cfg.WriteRoot, cfg.Manifest); err != nil {
+			return nil, nil, fmt.Errorf("saving scheme %s failed: %w", r.Generated.Name, err)
+		}
+		regenerated = append(regenerated, r.Generated.Name)
+		regeneratedPaths = append(regeneratedPaths, sharedSchemePath(r.ProjectPath, r.Generated.Name))
+		log.Donef("  Scheme %s generated in %s", r.Generated.Name, pathRelativeToWorkspace(r.ProjectPath, cfg.ContainerPath))
+	}
+
+	return regenerated, regeneratedPaths, nil
+}
+
+// regeneratedSchemes returns the schemes planned to be generated in place of malformed scheme files, mapped to their project path.
+func regeneratedSchemes(regenerations []schemeRegeneration) map[string][]generatedScheme {
+	projectToSchemes := map[string][]generatedScheme{}
+	for _, r := range regenerations {
+		if r.Found {
+			projectToSchemes[r.ProjectPath] = append(projectToSchemes[r.ProjectPath], r.Generated)
+		}
+	}
+	return projectToSchemes
+}
+
+// schemeBackupPath returns a path next to the scheme file, which is not listed as a scheme.
+func schemeBackupPath(pth string) (string, error) {
+	backupPath := pth + ".bak"
+	exist, err := pathutil.IsPathExists(backupPath)
+	if err != nil {
+		return "", err
+	}
+	if exist {
+		backupPath = fmt.Sprintf("%s.%s.bak", pth, time.Now().Format("20060102150405"))
+	}
+	return backupPath, nil
+}
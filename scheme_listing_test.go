@@ -0,0 +1,42 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+func TestWithoutSkippedSchemes(t *testing.T) {
+	projectPath := "/repo/App.xcodeproj"
+	skipped := []schemelist.MalformedScheme{
+		{Path: filepath.Join(projectPath, "xcshareddata", "xcschemes", "App.xcscheme")},
+		// User schemes are not overwritten by the shared ones.
+		{Path: filepath.Join(projectPath, "xcuserdata", "user.xcuserdatad", "xcschemes", "Widget.xcscheme")},
+	}
+	projectToSchemes := map[string][]generatedScheme{
+		projectPath: {
+			newGeneratedScheme(xcscheme.Scheme{Name: "App"}),
+			newGeneratedScheme(xcscheme.Scheme{Name: "Widget"}),
+		},
+		"/repo/Other.xcodeproj": {newGeneratedScheme(xcscheme.Scheme{Name: "App"})},
+	}
+
+	got := withoutSkippedSchemes(projectToSchemes, skipped, "/repo/App.xcworkspace")
+
+	if names := generatedSchemeNames(got[projectPath]); len(names) != 1 || names[0] != "Widget" {
+		t.Errorf("schemes of %s = %v, want [Widget]", projectPath, names)
+	}
+	if names := generatedSchemeNames(got["/repo/Other.xcodeproj"]); len(names) != 1 || names[0] != "App" {
+		t.Errorf("schemes of Other.xcodeproj = %v, want [App]", names)
+	}
+}
+
+func generatedSchemeNames(schemes []generatedScheme) []string {
+	var names []string
+	for _, scheme := range schemes {
+		names = append(names, scheme.Name)
+	}
+	return names
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcworkspace"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+// schemeOutput saves the generated schemes into the projects, or into a mirror tree in the output directory.
+type schemeOutput struct {
+	outputDir string
+	// sourceRoot is the common parent directory of the container and its projects, it is mirrored in the output directory.
+	sourceRoot    string
+	containerPath string
+	projectPaths  []string
+	// copyContainer copies the container and its project bundles into the mirror tree, before the schemes are saved into them.
+	copyContainer bool
+	writeRoot     string
+}
+
+func newSchemeOutput(cfg Config, projects []xcodeproject.XcodeProj) schemeOutput {
+	output := schemeOutput{
+		outputDir:     cfg.OutputDir,
+		sourceRoot:    filepath.Dir(cfg.ContainerPath),
+		containerPath: cfg.ContainerPath,
+		copyContainer: cfg.CopyContainer,
+		writeRoot:     cfg.WriteRoot,
+	}
+	if len(cfg.ContainerPaths) > 0 {
+		// Every container is mirrored into the same tree.
+		output.sourceRoot = commonParentDir(output.sourceRoot, containersDir(cfg.ContainerPaths))
+	}
+	for _, project := range projects {
+		output.projectPaths = append(output.projectPaths, project.Path)
+		output.sourceRoot = commonParentDir(output.sourceRoot, filepath.Dir(project.Path))
+	}
+	return output
+}
+
+func commonParentDir(a, b string) string {
+	for {
+		if isPathInDir(b, a) {
+			return a
+		}
+		if a == filepath.Dir(a) {
+			return a
+		}
+		a = filepath.Dir(a)
+	}
+}
+
+// path returns the path of the given source path in the output.
+func (o schemeOutput) path(sourcePath string) string {
+	if o.outputDir == "" {
+		return sourcePath
+	}
+
+	rel, err := filepath.Rel(o.sourceRoot, sourcePath)
+	if err != nil {
+		log.Warnf("%s", err)
+		rel = filepath.Base(sourcePath)
+	}
+	return filepath.Join(o.outputDir, rel)
+}
+
+// containerCopyPath returns the path of the container's copy, empty if the container is not copied.
+func (o schemeOutput) containerCopyPath() string {
+	if o.outputDir == "" || !o.copyContainer {
+		return ""
+	}
+	return o.path(o.containerPath)
+}
+
+// save saves the schemes of the projects, all or nothing, recording only the ones saved into the source tree.
+func (o schemeOutput) save(projectPaths []string, projectToSchemes map[string][]generatedScheme, manifest *fileManifest) error {
+	if o.outputDir == "" {
+		return saveSharedSchemes(projectPaths, projectToSchemes, o.writeRoot, manifest)
+	}
+
+	if o.copyContainer {
+		if err := o.copyBundles(); err != nil {
+			return err
+		}
+	}
+
+	var outputProjectPaths []string
+	outputProjectToSchemes := map[string][]generatedScheme{}
+	for _, projectPath := range projectPaths {
+		outputProjectPath := o.path(projectPath)
+		outputProjectPaths = append(outputProjectPaths, outputProjectPath)
+		outputProjectToSchemes[outputProjectPath] = projectToSchemes[projectPath]
+	}
+
+	// The output directory is configured explicitly, it is not confined to the write root.
+	return saveSharedSchemes(outputProjectPaths, outputProjectToSchemes, "", nil)
+}
+
+// copyBundles copies the container and its projects into the mirror tree, resolving their files in the source directory.
+func (o schemeOutput) copyBundles() error {
+	bundles := []string{o.containerPath}
+	for _, projectPath := range o.projectPaths {
+		if projectPath != o.containerPath {
+			bundles = append(bundles, projectPath)
+		}
+	}
+
+	fmt.Println()
+	log.Infof("Copying %s to the output directory...", filepath.Base(o.containerPath))
+	for _, bundle := range bundles {
+		copyPath := o.path(bundle)
+		if err := os.RemoveAll(copyPath); err != nil {
+			return fmt.Errorf("failed to remove the previous copy of %s: %w", filepath.Base(bundle), err)
+		}
+		if err := copyDir(bundle, copyPath); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", filepath.Base(bundle), err)
+		}
+		log.Printf("Copied %s to %s", pathRelativeToWorkspace(bundle, o.containerPath), copyPath)
+	}
+
+	for _, projectPath := range o.projectPaths {
+		if err := setProjectDir(o.path(projectPath), filepath.Dir(projectPath)); err != nil {
+			return fmt.Errorf("failed to update the copy of %s: %w", filepath.Base(projectPath), err)
+		}
+	}
+
+	if xcworkspace.IsWorkspace(o.containerPath) {
+		if err := o.relocateWorkspaceFiles(); err != nil {
+			return fmt.Errorf("failed to update the copy of %s: %w", filepath.Base(o.containerPath), err)
+		}
+	}
+
+	return nil
+}
+
+// relocateWorkspaceFiles points the file references of the workspace's copy, except the copied projects, at the original files.
+func (o schemeOutput) relocateWorkspaceFiles() error {
+	copyPath := o.path(o.containerPath)
+	contentsPath := filepath.Join(copyPath, "contents.xcworkspacedata")
+	content, err := os.ReadFile(contentsPath)
+	if err != nil {
+		return err
+	}
+	doc, err := parseXMLDocument(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", contentsPath, err)
+	}
+
+	copied := map[string]bool{}
+	for _, projectPath := range o.projectPaths {
+		copied[projectPath] = true
+	}
+
+	// The locations are resolved as in the original workspace.
+	locations := schemelist.WorkspaceLocations{WorkspacePath: o.containerPath}
+	var relocate func(element *xmlElement, groupDir string) error
+	relocate = func(element *xmlElement, groupDir string) error {
+		for _, child := range element.Children {
+			if child.Element == nil {
+				continue
+			}
+			location, ok := child.Element.attr("location")
+			if !ok {
+				continue
+			}
+			pth, err := locations.Resolve(location, groupDir)
+			if err != nil {
+				return err
+			}
+
+			switch child.Element.Name {
+			case "Group":
+				if err := relocate(child.Element, pth); err != nil {
+					return err
+				}
+			case "FileRef":
+				locationType, _, _ := strings.Cut(location, ":")
+				if copied[pth] || locationType == "absolute" || locationType == "developer" {
+					continue
+				}
+				rel, err := filepath.Rel(filepath.Dir(copyPath), pth)
+				if err != nil {
+					return err
+				}
+				child.Element.setAttr("location", "container:"+filepath.ToSlash(rel))
+			}
+		}
+		return nil
+	}
+	if err := relocate(doc.Root, filepath.Dir(o.containerPath)); err != nil {
+		return err
+	}
+
+	contents, err := doc.Marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(contentsPath, contents, 0600)
+}
+
+// setProjectDir sets the project directory of the project to the given directory, relative to the project.
+func setProjectDir(projectPath, dir string) error {
+	project, err := xcodeproject.Open(projectPath)
+	if err != nil {
+		return err
+	}
+
+	objects, err := project.RawProj.Object("objects")
+	if err != nil {
+		return err
+	}
+	rawProject, err := objects.Object(project.Proj.ID)
+	if err != nil {
+		return err
+	}
+
+	projectDirPath, err := rawProject.String("projectDirPath")
+	if err != nil {
+		projectDirPath = ""
+	}
+	if filepath.IsAbs(projectDirPath) {
+		return nil
+	}
+	rel, err := filepath.Rel(filepath.Dir(projectPath), filepath.Join(dir, projectDirPath))
+	if err != nil {
+		return err
+	}
+	rawProject["projectDirPath"] = filepath.ToSlash(rel)
+
+	return project.Save()
+}
+
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(pth string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, pth)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(pth)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(pth, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := in.Close(); err != nil {
+			log.Warnf("Failed to close %s: %s", src, err)
+		}
+	}()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// schemes adds the schemes saved into the output directory to the schemes of their source projects.
+func (o schemeOutput) schemes(containerToSchemes map[string][]xcscheme.Scheme, projectPaths []string) (map[string][]xcscheme.Scheme, error) {
+	if o.outputDir == "" {
+		return containerToSchemes, nil
+	}
+
+	merged := map[string][]xcscheme.Scheme{}
+	for containerPath, schemes := range containerToSchemes {
+		merged[containerPath] = schemes
+	}
+
+	for _, projectPath := range projectPaths {
+		schemes, _, _, err := schemelist.LocationSchemes(o.path(projectPath))
+		if err != nil {
+			return nil, err
+		}
+		for _, scheme := range schemes {
+			// A copied project also contains the schemes of the source project.
+			if scheme.IsShared && !containsSharedScheme(merged[projectPath], scheme.Name) {
+				merged[projectPath] = append(merged[projectPath], scheme)
+			}
+		}
+	}
+
+	return merged, nil
+}
+
+func containsSharedScheme(schemes []xcscheme.Scheme, name string) bool {
+	for _, scheme := range schemes {
+		if scheme.IsShared && schemelist.IsSameSchemeName(scheme.Name, name) {
+			return true
+		}
+	}
+	return false
+}
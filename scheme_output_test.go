@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRelocateWorkspaceFiles(t *testing.T) {
+	root := t.TempDir()
+	workspacePath := filepath.Join(root, "src", "App.xcworkspace")
+	copyPath := filepath.Join(root, "out", "App.xcworkspace")
+	contents := `<?xml version="1.0" encoding="UTF-8"?>
+<Workspace
+   version = "1.0">
+   <FileRef
+      location = "group:App.xcodeproj">
+   </FileRef>
+   <Group
+      location = "group:Pods"
+      name = "Pods">
+      <FileRef
+         location = "group:Pods.xcodeproj">
+      </FileRef>
+      <FileRef
+         location = "group:Podfile.lock">
+      </FileRef>
+   </Group>
+   <FileRef
+      location = "group:README.md">
+   </FileRef>
+   <FileRef
+      location = "absolute:/opt/Shared.xcconfig">
+   </FileRef>
+</Workspace>
+`
+	// Only the projects are copied, the other files stay in the source directory.
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<Workspace
+   version = "1.0">
+   <FileRef
+      location = "group:App.xcodeproj">
+   </FileRef>
+   <Group
+      location = "group:Pods"
+      name = "Pods">
+      <FileRef
+         location = "group:Pods.xcodeproj">
+      </FileRef>
+      <FileRef
+         location = "container:../src/Pods/Podfile.lock">
+      </FileRef>
+   </Group>
+   <FileRef
+      location = "container:../src/README.md">
+   </FileRef>
+   <FileRef
+      location = "absolute:/opt/Shared.xcconfig">
+   </FileRef>
+</Workspace>
+`
+	if err := os.MkdirAll(copyPath, 0700); err != nil {
+		t.Fatal(err)
+	}
+	contentsPath := filepath.Join(copyPath, "contents.xcworkspacedata")
+	if err := os.WriteFile(contentsPath, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	output := schemeOutput{
+		outputDir:     filepath.Join(root, "out"),
+		sourceRoot:    filepath.Dir(workspacePath),
+		containerPath: workspacePath,
+		projectPaths: []string{
+			filepath.Join(root, "src", "App.xcodeproj"),
+			filepath.Join(root, "src", "Pods", "Pods.xcodeproj"),
+		},
+	}
+	if err := output.relocateWorkspaceFiles(); err != nil {
+		t.Fatalf("relocateWorkspaceFiles() error = %v", err)
+	}
+
+	got, err := os.ReadFile(contentsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("relocated workspace:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitrise-io/go-utils/log"
+)
+
+const (
+	schemePatchFileName = "recreate_user_schemes.patch"
+	schemePatchAuthor   = "Recreate User Schemes Step <noreply@bitrise.io>"
+	schemePatchSubject  = "Share the generated Xcode Schemes"
+)
+
+// schemePatchFile is a new scheme file of the patch.
+type schemePatchFile struct {
+	// Path is relative to the repository root, with forward slashes.
+	Path     string
+	Scheme   string
+	Contents []byte
+}
+
+// schemePatch is the patch of the new scheme files of one git repository.
+type schemePatch struct {
+	RepositoryRoot string
+	Files          []schemePatchFile
+}
+
+// findRepositoryRoot returns the closest parent directory of the given path with a .git directory (or file, for worktrees and submodules).
+func findRepositoryRoot(pth string) (string, bool, error) {
+	for dir := pth; ; dir = filepath.Dir(dir) {
+		_, err := os.Lstat(filepath.Join(dir, ".git"))
+		if err == nil {
+			return dir, true, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", false, err
+		}
+		if dir == filepath.Dir(dir) {
+			return "", false, nil
+		}
+	}
+}
+
+// newSchemePatches returns the newly generated (not regenerated) schemes, grouped by the git repository of their source project.
+func newSchemePatches(result Result) ([]schemePatch, error) {
+	var patches []schemePatch
+	patchIndex := map[string]int{}
+	for _, projectPath := range sortedContainerPaths(result.ContainerToSchemes) {
+		var repositoryRoot string
+		for _, scheme := range result.ContainerToSchemes[projectPath] {
+			if !scheme.IsShared || !containsPath(result.GeneratedSchemePaths, scheme.Path) || containsPath(result.RegeneratedSchemePaths, scheme.Path) {
+				continue
+			}
+
+			if repositoryRoot == "" {
+				root, found, err := findRepositoryRoot(filepath.Dir(projectPath))
+				if err != nil {
+					return nil, fmt.Errorf("failed to find the repository root of %s: %w", filepath.Base(projectPath), err)
+				}
+				if !found {
+					log.Warnf("%s is not in a git repository, its Schemes are not added to the patch", filepath.Base(projectPath))
+					break
+				}
+				repositoryRoot = root
+			}
+
+			sourcePath := filepath.Join(projectPath, "xcshareddata", "xcschemes", filepath.Base(scheme.Path))
+			rel, err := filepath.Rel(repositoryRoot, sourcePath)
+			if err != nil {
+				return nil, err
+			}
+
+			contents, err := os.ReadFile(scheme.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read scheme %s: %w", scheme.Name, err)
+			}
+
+			i, ok := patchIndex[repositoryRoot]
+			if !ok {
+				i = len(patches)
+				patchIndex[repositoryRoot] = i
+				patches = append(patches, schemePatch{RepositoryRoot: repositoryRoot})
+			}
+			patches[i].Files = append(patches[i].Files, schemePatchFile{Path: filepath.ToSlash(rel), Scheme: scheme.Name, Contents: contents})
+		}
+	}
+	return patches, nil
+}
+
+// schemePatchFileNames returns the patch file names, adding the repository root name if there are several repositories.
+func schemePatchFileNames(patches []schemePatch) []string {
+	if len(patches) == 1 {
+		return []string{schemePatchFileName}
+	}
+
+	ext := filepath.Ext(schemePatchFileName)
+	base := strings.TrimSuffix(schemePatchFileName, ext)
+	names := make([]string, len(patches))
+	used := map[string]bool{}
+	for i, patch := range patches {
+		name := fmt.Sprintf("%s_%s%s", base, filepath.Base(patch.RepositoryRoot), ext)
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s_%s_%d%s", base, filepath.Base(patch.RepositoryRoot), n, ext)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// formatSchemePatch returns the files as a `git format-patch` style mail, which can be applied with `git am` or `git apply`.
+func formatSchemePatch(files []schemePatchFile, schemes []string, date time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From 0000000000000000000000000000000000000000 Mon Sep 17 00:00:00 2001\n")
+	fmt.Fprintf(&b, "From: %s\n", schemePatchAuthor)
+	fmt.Fprintf(&b, "Date: %s\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Subject: [PATCH] %s\n", schemePatchSubject)
+	fmt.Fprintf(&b, "\n")
+	fmt.Fprintf(&b, "The Schemes were generated on CI, as they were not shared:\n")
+	for _, scheme := range schemes {
+		fmt.Fprintf(&b, "- %s\n", scheme)
+	}
+	fmt.Fprintf(&b, "---\n")
+
+	lineCounts := make([]int, len(files))
+	maxPathLength, maxLineCount := 0, 0
+	for i, file := range files {
+		lineCounts[i] = len(patchLines(file.Contents))
+		maxPathLength = maxInt(maxPathLength, len(quotePatchPath(file.Path)))
+		maxLineCount = maxInt(maxLineCount, lineCounts[i])
+	}
+	countWidth := len(fmt.Sprint(maxLineCount))
+	var insertions int
+	for i, file := range files {
+		// The graph is scaled down like git does, to fit in the line.
+		bars := lineCounts[i]
+		if maxLineCount > 50 {
+			bars = (lineCounts[i]*50 + maxLineCount - 1) / maxLineCount
+		}
+		fmt.Fprintf(&b, " %-*s | %*d %s\n", maxPathLength, quotePatchPath(file.Path), countWidth, lineCounts[i], strings.Repeat("+", bars))
+		insertions += lineCounts[i]
+	}
+	fmt.Fprintf(&b, " %d file%s changed, %d insertion%s(+)\n", len(files), plural(len(files)), insertions, plural(insertions))
+	fmt.Fprintf(&b, "\n")
+
+	for _, file := range files {
+		fmt.Fprintf(&b, "diff --git %s %s\n", quotePatchPath("a/"+file.Path), quotePatchPath("b/"+file.Path))
+		fmt.Fprintf(&b, "new file mode 100644\n")
+		fmt.Fprintf(&b, "index 0000000..%s\n", gitBlobHash(file.Contents)[:7])
+		b.WriteString(unifiedDiff("/dev/null", quotePatchPath("b/"+file.Path), nil, patchLines(file.Contents)))
+		if len(file.Contents) > 0 && file.Contents[len(file.Contents)-1] != '\n' {
+			fmt.Fprintf(&b, "\\ No newline at end of file\n")
+		}
+	}
+
+	fmt.Fprintf(&b, "-- \n")
+	fmt.Fprintf(&b, "recreate-user-schemes\n\n")
+
+	return b.String()
+}
+
+func patchLines(contents []byte) []string {
+	if len(contents) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+}
+
+func plural(count int) string {
+	if count == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// gitBlobHash returns the object name git gives to the file contents.
+func gitBlobHash(contents []byte) string {
+	hash := sha1.New()
+	fmt.Fprintf(hash, "blob %d\x00", len(contents))
+	_, _ = hash.Write(contents)
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+// quotePatchPath quotes the path like git does, if it contains control, quote, backslash or non-ASCII characters.
+func quotePatchPath(pth string) string {
+	var b strings.Builder
+	quoted := false
+	for i := 0; i < len(pth); i++ {
+		c := pth[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+			quoted = true
+		case c == '\t':
+			b.WriteString(`\t`)
+			quoted = true
+		case c == '\n':
+			b.WriteString(`\n`)
+			quoted = true
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\%03o", c)
+			quoted = true
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	if !quoted {
+		return pth
+	}
+	return `"` + b.String() + `"`
+}
+
+// writeSchemePatch saves the newly generated scheme files to the deploy directory, as one patch per git repository, relative to the repository root.
+func writeSchemePatch(cfg Config, result Result) error {
+	patches, err := newSchemePatches(result)
+	if err != nil {
+		return err
+	}
+	if len(patches) == 0 {
+		return nil
+	}
+
+	deployDir, err := filepath.Abs(cfg.DeployDir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	for i, name := range schemePatchFileNames(patches) {
+		patch := patches[i]
+		var schemes []string
+		for _, file := range patch.Files {
+			schemes = append(schemes, file.Scheme)
+		}
+
+		pth := filepath.Join(deployDir, name)
+		if err := os.WriteFile(pth, []byte(formatSchemePatch(patch.Files, schemes, time.Now())), 0600); err != nil {
+			return fmt.Errorf("failed to write the patch: %w", err)
+		}
+
+		log.Printf("Patch of the generated Schemes saved to: %s", pth)
+		log.Printf("Commit the Schemes by running: git -C %s am %s", patch.RepositoryRoot, pth)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+)
+
+func TestNewSchemePatches(t *testing.T) {
+	root := t.TempDir()
+	newScheme := func(projectPath, name string) xcscheme.Scheme {
+		pth := filepath.Join(projectPath, "xcshareddata", "xcschemes", name+".xcscheme")
+		if err := os.MkdirAll(filepath.Dir(pth), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(pth, []byte(name+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return xcscheme.Scheme{Name: name, Path: pth, IsShared: true}
+	}
+
+	// Two repositories and a project outside of any repository.
+	for _, repository := range []string{"app", "shared"} {
+		if err := os.MkdirAll(filepath.Join(root, repository, ".git"), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	appProject := filepath.Join(root, "app", "App.xcodeproj")
+	coreProject := filepath.Join(root, "shared", "Core", "Core.xcodeproj")
+	looseProject := filepath.Join(root, "Loose.xcodeproj")
+	appScheme, brokenScheme := newScheme(appProject, "App"), newScheme(appProject, "Broken")
+	coreScheme, looseScheme := newScheme(coreProject, "Core"), newScheme(looseProject, "Loose")
+	// The committed schemes of the other project are left out, even if a generated scheme has the same name.
+	result := Result{
+		ContainerToSchemes: map[string][]xcscheme.Scheme{
+			appProject:   {appScheme, brokenScheme, newScheme(appProject, "Existing"), newScheme(appProject, "Core")},
+			coreProject:  {coreScheme},
+			looseProject: {looseScheme},
+		},
+		GeneratedSchemes:       []string{"App", "Broken", "Core", "Loose"},
+		RegeneratedSchemes:     []string{"Broken"},
+		GeneratedSchemePaths:   []string{appScheme.Path, brokenScheme.Path, coreScheme.Path, looseScheme.Path},
+		RegeneratedSchemePaths: []string{brokenScheme.Path},
+	}
+
+	patches, err := newSchemePatches(result)
+	if err != nil {
+		t.Fatalf("newSchemePatches() error = %v", err)
+	}
+
+	want := []schemePatch{
+		{RepositoryRoot: filepath.Join(root, "app"), Files: []schemePatchFile{
+			{Path: "App.xcodeproj/xcshareddata/xcschemes/App.xcscheme", Scheme: "App", Contents: []byte("App\n")},
+		}},
+		{RepositoryRoot: filepath.Join(root, "shared"), Files: []schemePatchFile{
+			{Path: "Core/Core.xcodeproj/xcshareddata/xcschemes/Core.xcscheme", Scheme: "Core", Contents: []byte("Core\n")},
+		}},
+	}
+	if !reflect.DeepEqual(patches, want) {
+		t.Fatalf("newSchemePatches() = %+v, want %+v", patches, want)
+	}
+
+	if names := schemePatchFileNames(patches); !reflect.DeepEqual(names, []string{"recreate_user_schemes_app.patch", "recreate_user_schemes_shared.patch"}) {
+		t.Errorf("schemePatchFileNames() = %v", names)
+	}
+	if names := schemePatchFileNames(patches[:1]); !reflect.DeepEqual(names, []string{schemePatchFileName}) {
+		t.Errorf("schemePatchFileNames() of one patch = %v, want %s", names, schemePatchFileName)
+	}
+}
+
+func TestFormatSchemePatchApplies(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git is not installed")
+	}
+	git := func(dir string, args ...string) {
+		cmd := exec.Command(gitPath, append([]string{"-C", dir}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %s\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	root := t.TempDir()
+	git(root, "init", "-q")
+
+	// A quoted path, a file without a trailing newline, and one long enough to scale the graph.
+	long := strings.Repeat("<line/>\n", 80)
+	files := []schemePatchFile{
+		{Path: "App.xcodeproj/xcshareddata/xcschemes/App.xcscheme", Scheme: "App", Contents: []byte(long)},
+		{Path: "App.xcodeproj/xcshareddata/xcschemes/Tést \"UI\".xcscheme", Scheme: "Tést \"UI\"", Contents: []byte("<Scheme>\n</Scheme>")},
+	}
+	patch := formatSchemePatch(files, []string{"App", "Tést \"UI\""}, time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC))
+	patchPath := filepath.Join(t.TempDir(), schemePatchFileName)
+	if err := os.WriteFile(patchPath, []byte(patch), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	git(root, "apply", "--check", patchPath)
+	git(root, "apply", patchPath)
+	for _, file := range files {
+		got, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file.Path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(file.Contents) {
+			t.Errorf("%s = %q, want %q", file.Path, got, file.Contents)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+// referenceFix is a stale BuildableReference and its up-to-date version.
+type referenceFix struct {
+	Old xcscheme.BuildableReference
+	New xcscheme.BuildableReference
+}
+
+// changes describes the changed attributes of the reference.
+func (f referenceFix) changes() []string {
+	var changes []string
+	for _, attribute := range []struct{ name, old, new string }{
+		{"BlueprintIdentifier", f.Old.BlueprintIdentifier, f.New.BlueprintIdentifier},
+		{"BuildableName", f.Old.BuildableName, f.New.BuildableName},
+		{"BlueprintName", f.Old.BlueprintName, f.New.BlueprintName},
+		{"ReferencedContainer", f.Old.ReferencedContainer, f.New.ReferencedContainer},
+	} {
+		if attribute.old != attribute.new {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", attribute.name, attribute.old, attribute.new))
+		}
+	}
+	return changes
+}
+
+// repair fixes the stale BuildableReferences of the committed shared schemes, in place.
+func (g SchemeGenerator) repair(cfg Config, container schemelist.Container, containerToSchemes map[string][]xcscheme.Scheme) error {
+	fmt.Println()
+	log.Infof("Repairing shared Schemes...")
+
+	projects, missingProjects, err := container.Projects()
+	if err != nil {
+		return fmt.Errorf("getting projects failed: %w", err)
+	}
+
+	for _, missingProject := range missingProjects {
+		log.Warnf("Skipping project (%s), as it is not present", pathRelativeToWorkspace(missingProject, cfg.ContainerPath))
+	}
+
+	var repaired, untouched int
+	for _, containerPath := range sortedContainerPaths(containerToSchemes) {
+		for _, scheme := range containerToSchemes[containerPath] {
+			if !scheme.IsShared || scheme.Path == "" {
+				continue
+			}
+
+			schemePath := pathRelativeToWorkspace(scheme.Path, cfg.ContainerPath)
+			if err := checkWritePath(cfg.WriteRoot, scheme.Path); err != nil {
+				untouched++
+				fmt.Println()
+				log.Warnf("%s: left untouched, %s", schemePath, err)
+				continue
+			}
+
+			fixes, err := referenceFixes(scheme, containerPath, projects)
+			if err != nil {
+				untouched++
+				fmt.Println()
+				log.Warnf("%s: left untouched, %s", schemePath, err)
+				continue
+			}
+			if len(fixes) == 0 {
+				continue
+			}
+
+			fmt.Println()
+			log.Printf("%s:", schemePath)
+			for _, fix := range fixes {
+				log.Printf("- %s: %s", fix.Old.BlueprintName, strings.Join(fix.changes(), ", "))
+			}
+
+			if cfg.DryRun {
+				continue
+			}
+
+			if err := applyReferenceFixes(scheme.Path, fixes, cfg.WriteRoot, cfg.Manifest); err != nil {
+				return fmt.Errorf("repairing scheme %s failed: %w", schemePath, err)
+			}
+			repaired++
+		}
+	}
+
+	fmt.Println()
+	if cfg.DryRun {
+		log.Donef("Dry run, no Scheme is written.")
+	} else {
+		log.Donef("Repaired %d shared Scheme(s).", repaired)
+	}
+	if untouched > 0 {
+		log.Warnf("%d shared Scheme(s) could not be repaired unambiguously.", untouched)
+	}
+
+	return nil
+}
+
+// referenceFixes matches the references of the scheme to the targets by blueprint ID, then by name, failing on an ambiguous match.
+func referenceFixes(scheme xcscheme.Scheme, containerPath string, projects []xcodeproject.XcodeProj) ([]referenceFix, error) {
+	containerDir := filepath.Dir(containerPath)
+
+	var fixes []referenceFix
+	seen := map[xcscheme.BuildableReference]bool{}
+	for _, ref := range schemeReferences(scheme) {
+		if seen[ref.reference] {
+			continue
+		}
+		seen[ref.reference] = true
+
+		project, target, err := matchReference(ref.reference, containerDir, projects)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ref.element, err)
+		}
+
+		newReference := ref.reference
+		newReference.BlueprintIdentifier = target.ID
+		newReference.BlueprintName = target.Name
+		if target.ProductReference.Path != "" {
+			newReference.BuildableName = path.Base(target.ProductReference.Path)
+		}
+
+		if projectPath, err := ref.reference.ReferencedContainerAbsPath(containerDir); err != nil || projectPath != project.Path {
+			relPath, err := filepath.Rel(containerDir, project.Path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", ref.element, err)
+			}
+			newReference.ReferencedContainer = "container:" + filepath.ToSlash(relPath)
+		}
+
+		if newReference != ref.reference {
+			fixes = append(fixes, referenceFix{Old: ref.reference, New: newReference})
+		}
+	}
+
+	return fixes, nil
+}
+
+func matchReference(reference xcscheme.BuildableReference, containerDir string, projects []xcodeproject.XcodeProj) (xcodeproject.XcodeProj, xcodeproject.Target, error) {
+	candidates := projects
+	if projectPath, err := reference.ReferencedContainerAbsPath(containerDir); err == nil {
+		if exist, err := pathutil.IsPathExists(projectPath); err == nil && exist {
+			candidates = nil
+			for _, project := range projects {
+				if project.Path == projectPath {
+					candidates = append(candidates, project)
+				}
+			}
+			if len(candidates) == 0 {
+				project, err := xcodeproject.Open(projectPath)
+				if err != nil {
+					return xcodeproject.XcodeProj{}, xcodeproject.Target{}, fmt.Errorf("failed to open %s: %w", reference.ReferencedContainer, err)
+				}
+				candidates = append(candidates, project)
+			}
+		}
+	}
+
+	for _, match := range []func(xcodeproject.Proj) (xcodeproject.Target, bool){
+		func(proj xcodeproject.Proj) (xcodeproject.Target, bool) {
+			return proj.Target(reference.BlueprintIdentifier)
+		},
+		func(proj xcodeproject.Proj) (xcodeproject.Target, bool) {
+			return proj.TargetByName(reference.BlueprintName)
+		},
+	} {
+		var matchingProjects []xcodeproject.XcodeProj
+		var matchingTargets []xcodeproject.Target
+		for _, project := range candidates {
+			if target, ok := match(project.Proj); ok {
+				matchingProjects = append(matchingProjects, project)
+				matchingTargets = append(matchingTargets, target)
+			}
+		}
+
+		switch len(matchingTargets) {
+		case 0:
+			continue
+		case 1:
+			return matchingProjects[0], matchingTargets[0], nil
+		default:
+			var names []string
+			for _, project := range matchingProjects {
+				names = append(names, filepath.Base(project.Path))
+			}
+			return xcodeproject.XcodeProj{}, xcodeproject.Target{}, fmt.Errorf("target %s matches multiple projects (%s)", reference.BlueprintName, strings.Join(names, ", "))
+		}
+	}
+
+	return xcodeproject.XcodeProj{}, xcodeproject.Target{}, fmt.Errorf("no target matches %s (%s)", reference.BlueprintName, reference.BlueprintIdentifier)
+}
+
+// applyReferenceFixes rewrites the stale BuildableReference attributes in the scheme file, keeping the unknown elements and attributes.
+func applyReferenceFixes(pth string, fixes []referenceFix, writeRoot string, manifest *fileManifest) error {
+	if err := checkWritePath(writeRoot, pth); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(pth)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(pth)
+	if err != nil {
+		return err
+	}
+
+	doc, err := parseXMLDocument(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse scheme: %w", err)
+	}
+
+	doc.Root.walk(func(element *xmlElement) {
+		if element.Name != "BuildableReference" {
+			return
+		}
+
+		reference := buildableReferenceOfElement(element)
+		for _, fix := range fixes {
+			if reference != fix.Old {
+				continue
+			}
+
+			element.setAttr("BlueprintIdentifier", fix.New.BlueprintIdentifier)
+			element.setAttr("BuildableName", fix.New.BuildableName)
+			element.setAttr("BlueprintName", fix.New.BlueprintName)
+			element.setAttr("ReferencedContainer", fix.New.ReferencedContainer)
+			return
+		}
+	})
+
+	content, err = doc.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := manifest.recordFile(pth); err != nil {
+		return fmt.Errorf("failed to record the scheme in the manifest: %w", err)
+	}
+
+	return os.WriteFile(pth, content, info.Mode())
+}
+
+func buildableReferenceOfElement(element *xmlElement) xcscheme.BuildableReference {
+	var reference xcscheme.BuildableReference
+	reference.BuildableIdentifier, _ = element.attr("BuildableIdentifier")
+	reference.BlueprintIdentifier, _ = element.attr("BlueprintIdentifier")
+	reference.BuildableName, _ = element.attr("BuildableName")
+	reference.BlueprintName, _ = element.attr("BlueprintName")
+	reference.ReferencedContainer, _ = element.attr("ReferencedContainer")
+	return reference
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"gopkg.in/yaml.v3"
+)
+
+// schemeSpec describes the schemes to generate instead of the default schemes, read from the scheme_spec_path YAML or JSON file.
+type schemeSpec struct {
+	Schemes []schemeSpecEntry `yaml:"schemes"`
+}
+
+// schemeSpecEntry describes a single scheme.
+type schemeSpecEntry struct {
+	Name string `yaml:"name"`
+	// Project is the file name or the container relative path of the scheme's project.
+	// It can be omitted if the build targets are present in a single project.
+	Project              string                   `yaml:"project"`
+	BuildTargets         []string                 `yaml:"build_targets"`
+	TestTargets          []string                 `yaml:"test_targets"`
+	Configurations       schemeSpecConfigurations `yaml:"configurations"`
+	EnvironmentVariables map[string]string        `yaml:"environment_variables"`
+	// TestPlans are the project relative paths of the scheme's test plans, the first one is the default.
+	TestPlans []string `yaml:"test_plans"`
+}
+
+// schemeSpecConfigurations are the build configurations per scheme action, empty ones default to Xcode's.
+type schemeSpecConfigurations struct {
+	Test    string `yaml:"test"`
+	Launch  string `yaml:"launch"`
+	Profile string `yaml:"profile"`
+	Analyze string `yaml:"analyze"`
+	Archive string `yaml:"archive"`
+}
+
+func openSchemeSpec(pth string) (schemeSpec, error) {
+	content, err := os.ReadFile(pth)
+	if err != nil {
+		return schemeSpec{}, err
+	}
+
+	var spec schemeSpec
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&spec); err != nil {
+		return schemeSpec{}, fmt.Errorf("failed to parse scheme spec (%s): %w", pth, err)
+	}
+
+	if err := spec.validate(); err != nil {
+		return schemeSpec{}, fmt.Errorf("invalid scheme spec (%s): %w", pth, err)
+	}
+
+	return spec, nil
+}
+
+func (s schemeSpec) validate() error {
+	if len(s.Schemes) == 0 {
+		return errors.New("no schemes defined")
+	}
+
+	names := map[string]bool{}
+	for i, entry := range s.Schemes {
+		if entry.Name == "" {
+			return fmt.Errorf("scheme #%d has no name", i+1)
+		}
+		if names[entry.Name] {
+			return fmt.Errorf("scheme %s is defined multiple times", entry.Name)
+		}
+		names[entry.Name] = true
+
+		if len(entry.BuildTargets) == 0 {
+			return fmt.Errorf("scheme %s has no build targets", entry.Name)
+		}
+	}
+
+	return nil
+}
+
+// render validates the spec against the projects, and returns the schemes mapped to the project to save them in.
+func (s schemeSpec) render(projects []xcodeproject.XcodeProj, containerPath string) (map[string][]generatedScheme, error) {
+	projectToSchemes := map[string][]generatedScheme{}
+	var errs []error
+	for _, entry := range s.Schemes {
+		project, err := entry.project(projects, containerPath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("scheme %s: %w", entry.Name, err))
+			continue
+		}
+
+		scheme, err := entry.scheme(project)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("scheme %s: %w", entry.Name, err))
+			continue
+		}
+
+		projectToSchemes[project.Path] = append(projectToSchemes[project.Path], scheme)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return projectToSchemes, nil
+}
+
+func (e schemeSpecEntry) project(projects []xcodeproject.XcodeProj, containerPath string) (xcodeproject.XcodeProj, error) {
+	if e.Project != "" {
+		for _, project := range projects {
+			if filepath.Base(project.Path) == e.Project || project.Name == e.Project || pathRelativeToWorkspace(project.Path, containerPath) == filepath.Clean(e.Project) {
+				return project, nil
+			}
+		}
+		return xcodeproject.XcodeProj{}, fmt.Errorf("project %s not found", e.Project)
+	}
+
+	var candidates []xcodeproject.XcodeProj
+	for _, project := range projects {
+		if _, ok := project.Proj.TargetByName(e.BuildTargets[0]); ok {
+			candidates = append(candidates, project)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return xcodeproject.XcodeProj{}, fmt.Errorf("target %s not found in any project", e.BuildTargets[0])
+	case 1:
+		return candidates[0], nil
+	default:
+		var names []string
+		for _, candidate := range candidates {
+			names = append(names, filepath.Base(candidate.Path))
+		}
+		return xcodeproject.XcodeProj{}, fmt.Errorf("target %s found in multiple projects (%s), set the scheme's project", e.BuildTargets[0], strings.Join(names, ", "))
+	}
+}
+
+func (e schemeSpecEntry) scheme(project xcodeproject.XcodeProj) (generatedScheme, error) {
+	projectName := filepath.Base(project.Path)
+
+	var errs []error
+	var buildTargets []xcodeproject.Target
+	for _, name := range e.BuildTargets {
+		target, ok := project.Proj.TargetByName(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("build target %s not found in %s", name, projectName))
+			continue
+		}
+		buildTargets = append(buildTargets, target)
+	}
+
+	var testTargets []xcodeproject.Target
+	for _, name := range e.TestTargets {
+		target, ok := project.Proj.TargetByName(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("test target %s not found in %s", name, projectName))
+			continue
+		}
+		if !target.IsTest() {
+			errs = append(errs, fmt.Errorf("test target %s in %s is not a test target", name, projectName))
+			continue
+		}
+		testTargets = append(testTargets, target)
+	}
+
+	for _, action := range []struct{ name, configuration string }{
+		{"test", e.Configurations.Test},
+		{"launch", e.Configurations.Launch},
+		{"profile", e.Configurations.Profile},
+		{"analyze", e.Configurations.Analyze},
+		{"archive", e.Configurations.Archive},
+	} {
+		if action.configuration == "" {
+			continue
+		}
+		if !hasConfiguration(project.Proj.BuildConfigurationList, action.configuration) {
+			errs = append(errs, fmt.Errorf("%s configuration %s not found in %s", action.name, action.configuration, projectName))
+			continue
+		}
+		// xcodebuild falls back to the target's default configuration, if the target does not define the scheme's one.
+		for _, target := range append(append([]xcodeproject.Target{}, buildTargets...), testTargets...) {
+			if !hasConfiguration(target.BuildConfigurationList, action.configuration) {
+				errs = append(errs, fmt.Errorf("%s configuration %s not found in target %s", action.name, action.configuration, target.Name))
+			}
+		}
+	}
+
+	for _, testPlan := range e.TestPlans {
+		if exist, err := pathutil.IsPathExists(filepath.Join(filepath.Dir(project.Path), testPlan)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to check if test plan %s exists: %w", testPlan, err))
+		} else if !exist {
+			errs = append(errs, fmt.Errorf("test plan %s not found next to %s", testPlan, projectName))
+		}
+	}
+
+	if len(errs) > 0 {
+		return generatedScheme{}, errors.Join(errs...)
+	}
+
+	mainTarget := buildTargets[0]
+	configurations := e.Configurations
+	if configurations.Test == "" {
+		configurations.Test = debugConfigurationName(mainTarget)
+	}
+	if configurations.Launch == "" {
+		configurations.Launch = debugConfigurationName(mainTarget)
+	}
+	if configurations.Profile == "" {
+		configurations.Profile = releaseConfigurationName(mainTarget)
+	}
+	if configurations.Analyze == "" {
+		configurations.Analyze = debugConfigurationName(mainTarget)
+	}
+	if configurations.Archive == "" {
+		configurations.Archive = releaseConfigurationName(mainTarget)
+	}
+
+	scheme := newGeneratedScheme(xcscheme.Scheme{
+		Name:               e.Name,
+		LastUpgradeVersion: lastUpgradeVersion,
+		Version:            schemeVersion,
+		BuildAction:        newBuildAction(buildTargets, projectName),
+		TestAction:         newTestAction(mainTarget, testTargets, e.TestPlans, configurations.Test, projectName),
+		LaunchAction:       newLaunchAction(mainTarget, configurations.Launch, projectName),
+		ProfileAction:      newProfileAction(mainTarget, configurations.Profile, projectName),
+		AnalyzeAction:      xcscheme.AnalyzeAction{BuildConfiguration: configurations.Analyze},
+		ArchiveAction:      xcscheme.ArchiveAction{BuildConfiguration: configurations.Archive, RevealArchiveInOrganizer: yes},
+	})
+
+	var keys []string
+	for key := range e.EnvironmentVariables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		scheme.EnvironmentVariables = append(scheme.EnvironmentVariables, environmentVariable{
+			Key:       key,
+			Value:     e.EnvironmentVariables[key],
+			IsEnabled: yes,
+		})
+	}
+
+	return scheme, nil
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+)
+
+func newTestConfigurationList(names ...string) xcodeproject.ConfigurationList {
+	list := xcodeproject.ConfigurationList{DefaultConfigurationName: names[0]}
+	for _, name := range names {
+		list.BuildConfigurations = append(list.BuildConfigurations, xcodeproject.BuildConfiguration{Name: name})
+	}
+	return list
+}
+
+func newTestProject(path string, targets ...xcodeproject.Target) xcodeproject.XcodeProj {
+	return xcodeproject.XcodeProj{
+		Path: path,
+		Proj: xcodeproject.Proj{
+			BuildConfigurationList: newTestConfigurationList("Debug", "Release", "Staging"),
+			Targets:                targets,
+		},
+	}
+}
+
+func newTestTarget(id, name, productType string, configurations ...string) xcodeproject.Target {
+	if len(configurations) == 0 {
+		configurations = []string{"Debug", "Release", "Staging"}
+	}
+	return xcodeproject.Target{
+		Type:                   xcodeproject.NativeTargetType,
+		ID:                     id,
+		Name:                   name,
+		ProductType:            productType,
+		ProductReference:       xcodeproject.ProductReference{Path: name + ".app"},
+		BuildConfigurationList: newTestConfigurationList(configurations...),
+	}
+}
+
+func TestSchemeSpecValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    schemeSpec
+		wantErr string
+	}{
+		{
+			name:    "no schemes",
+			spec:    schemeSpec{},
+			wantErr: "no schemes defined",
+		},
+		{
+			name:    "missing name",
+			spec:    schemeSpec{Schemes: []schemeSpecEntry{{BuildTargets: []string{"App"}}}},
+			wantErr: "scheme #1 has no name",
+		},
+		{
+			name: "duplicate name",
+			spec: schemeSpec{Schemes: []schemeSpecEntry{
+				{Name: "App", BuildTargets: []string{"App"}},
+				{Name: "App", BuildTargets: []string{"Widget"}},
+			}},
+			wantErr: "scheme App is defined multiple times",
+		},
+		{
+			name:    "no build targets",
+			spec:    schemeSpec{Schemes: []schemeSpecEntry{{Name: "App"}}},
+			wantErr: "scheme App has no build targets",
+		},
+		{
+			name: "valid",
+			spec: schemeSpec{Schemes: []schemeSpecEntry{{Name: "App", BuildTargets: []string{"App"}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.validate()
+			checkError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestSchemeSpecRender(t *testing.T) {
+	const appType = "com.apple.product-type.application"
+	const testType = "com.apple.product-type.bundle.unit-test"
+
+	app := newTestProject("/repo/App.xcodeproj",
+		newTestTarget("A1", "App", appType),
+		newTestTarget("A2", "AppTests", testType),
+		newTestTarget("A3", "Widget", appType, "Debug", "Release"),
+	)
+	framework := newTestProject("/repo/Framework.xcodeproj",
+		newTestTarget("F1", "Framework", appType),
+		newTestTarget("F2", "Widget", appType),
+	)
+	projects := []xcodeproject.XcodeProj{app, framework}
+
+	tests := []struct {
+		name    string
+		entry   schemeSpecEntry
+		wantErr string
+	}{
+		{
+			name:    "unknown project",
+			entry:   schemeSpecEntry{Name: "App", Project: "Missing.xcodeproj", BuildTargets: []string{"App"}},
+			wantErr: "scheme App: project Missing.xcodeproj not found",
+		},
+		{
+			name:    "unknown target",
+			entry:   schemeSpecEntry{Name: "App", BuildTargets: []string{"Missing"}},
+			wantErr: "scheme App: target Missing not found in any project",
+		},
+		{
+			name:    "ambiguous target",
+			entry:   schemeSpecEntry{Name: "Widget", BuildTargets: []string{"Widget"}},
+			wantErr: "scheme Widget: target Widget found in multiple projects (App.xcodeproj, Framework.xcodeproj), set the scheme's project",
+		},
+		{
+			name:    "unknown build target in the project",
+			entry:   schemeSpecEntry{Name: "App", Project: "App.xcodeproj", BuildTargets: []string{"App", "Framework"}},
+			wantErr: "scheme App: build target Framework not found in App.xcodeproj",
+		},
+		{
+			name:    "unknown test target",
+			entry:   schemeSpecEntry{Name: "App", BuildTargets: []string{"App"}, TestTargets: []string{"FrameworkTests"}},
+			wantErr: "scheme App: test target FrameworkTests not found in App.xcodeproj",
+		},
+		{
+			name:    "test target is not a test",
+			entry:   schemeSpecEntry{Name: "App", BuildTargets: []string{"App"}, TestTargets: []string{"App"}},
+			wantErr: "scheme App: test target App in App.xcodeproj is not a test target",
+		},
+		{
+			name:    "unknown project configuration",
+			entry:   schemeSpecEntry{Name: "App", BuildTargets: []string{"App"}, Configurations: schemeSpecConfigurations{Archive: "AppStore"}},
+			wantErr: "scheme App: archive configuration AppStore not found in App.xcodeproj",
+		},
+		{
+			name:    "unknown target configuration",
+			entry:   schemeSpecEntry{Name: "Widget", Project: "App.xcodeproj", BuildTargets: []string{"Widget"}, Configurations: schemeSpecConfigurations{Launch: "Staging"}},
+			wantErr: "scheme Widget: launch configuration Staging not found in target Widget",
+		},
+		{
+			name:  "valid",
+			entry: schemeSpecEntry{Name: "App", BuildTargets: []string{"App"}, TestTargets: []string{"AppTests"}, Configurations: schemeSpecConfigurations{Launch: "Staging"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projectToSchemes, err := schemeSpec{Schemes: []schemeSpecEntry{tt.entry}}.render(projects, "/repo/App.xcworkspace")
+			checkError(t, err, tt.wantErr)
+			if tt.wantErr == "" && len(projectToSchemes[app.Path]) != 1 {
+				t.Errorf("render() = %v, want a single scheme in %s", projectToSchemes, app.Path)
+			}
+		})
+	}
+}
+
+func TestSchemeSpecRenderDefaultTestAction(t *testing.T) {
+	project := newTestProject("/repo/App.xcodeproj", newTestTarget("A1", "App", "com.apple.product-type.application"))
+
+	projectToSchemes, err := schemeSpec{Schemes: []schemeSpecEntry{{Name: "App", BuildTargets: []string{"App"}}}}.render([]xcodeproject.XcodeProj{project}, project.Path)
+	if err != nil {
+		t.Fatalf("render() error = %v", err)
+	}
+
+	got := projectToSchemes[project.Path][0].TestAction
+	want := defaultTestAction("Debug")
+	if got.BuildConfiguration != want.BuildConfiguration || got.SelectedDebuggerIdentifier != want.SelectedDebuggerIdentifier ||
+		got.SelectedLauncherIdentifier != want.SelectedLauncherIdentifier || got.ShouldUseLaunchSchemeArgsEnv != want.ShouldUseLaunchSchemeArgsEnv {
+		t.Errorf("TestAction = %+v, want %+v", got, want)
+	}
+	if got.MacroExpansion.BuildableReference.BlueprintIdentifier != "" {
+		t.Errorf("TestAction.MacroExpansion = %+v, want none without tests", got.MacroExpansion)
+	}
+}
+
+// checkError fails the test if err does not match wantErr, an empty wantErr expects no error.
+func checkError(t *testing.T, err error, wantErr string) {
+	t.Helper()
+	switch {
+	case wantErr == "" && err != nil:
+		t.Fatalf("unexpected error: %v", err)
+	case wantErr != "" && err == nil:
+		t.Fatalf("expected error: %s", wantErr)
+	case wantErr != "" && !strings.Contains(err.Error(), wantErr):
+		t.Fatalf("error = %v, want %s", err, wantErr)
+	}
+}
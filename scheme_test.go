@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+)
+
+func openTestScheme(t *testing.T, pth string) xcscheme.Scheme {
+	t.Helper()
+	contents, err := os.ReadFile(pth)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", pth, err)
+	}
+	var scheme xcscheme.Scheme
+	if err := xml.Unmarshal(contents, &scheme); err != nil {
+		t.Fatalf("failed to parse %s: %v", pth, err)
+	}
+	return scheme
+}
+
+// ios-simple-objc.xcscheme (Xcode 8) and BullsEye.xcscheme (Xcode 13, with test plans) are saved by Xcode,
+// App-no-tests.xcscheme and App-execution-actions.xcscheme are hand-written regression goldens for a target without tests.
+func TestGeneratedSchemeMarshal(t *testing.T) {
+	tests := []struct {
+		name   string
+		golden string
+		// base is the scheme file parsed into the go-xcode model, defaults to the golden file.
+		base   string
+		modify func(*xcscheme.Scheme)
+		extend func(generatedScheme) generatedScheme
+	}{
+		{
+			name:   "legacy layout with test targets",
+			golden: "ios-simple-objc.xcscheme",
+		},
+		{
+			name:   "test plans and test targets",
+			golden: "BullsEye.xcscheme",
+		},
+		{
+			name:   "without test targets",
+			golden: "App-no-tests.xcscheme",
+		},
+		{
+			name:   "empty test action of a recreated scheme",
+			golden: "App-no-tests.xcscheme",
+			modify: func(scheme *xcscheme.Scheme) {
+				// xcodeproj.XcodeProj.ReCreateSchemes leaves the test action empty, if the target has no tests.
+				scheme.TestAction = xcscheme.TestAction{}
+			},
+		},
+		{
+			name:   "escaped attribute values",
+			golden: "App-execution-actions.xcscheme",
+			base:   "App-no-tests.xcscheme",
+			extend: func(scheme generatedScheme) generatedScheme {
+				scheme = addExecutionScripts(scheme, []executionScript{{
+					Action:     buildSchemeAction,
+					Phase:      preExecutionPhase,
+					ScriptPath: "scripts/generate_config.sh",
+					ScriptText: "#!/bin/sh\nset -e\n\necho \"API_URL=${API_URL}\" > \"${SRCROOT}/Config.xcconfig\"\n[ -n \"$CI\" ] && echo 'CI build' < /dev/null\nprintf 'a\tb'\n",
+				}})
+				scheme.EnvironmentVariables = []environmentVariable{{Key: "GREETING", Value: "Hello \"World\"\n& bye", IsEnabled: yes}}
+				return scheme
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := tt.base
+			if base == "" {
+				base = tt.golden
+			}
+			scheme := openTestScheme(t, filepath.Join("testdata", base))
+			if tt.modify != nil {
+				tt.modify(&scheme)
+			}
+			generated := newGeneratedScheme(scheme)
+			if tt.extend != nil {
+				generated = tt.extend(generated)
+			}
+
+			got, err := generated.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+
+			want, err := os.ReadFile(filepath.Join("testdata", tt.golden))
+			if err != nil {
+				t.Fatalf("failed to read golden file: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Marshal() differs from %s:\n%s", tt.golden, unifiedDiff(tt.golden, "Marshal()", strings.Split(string(want), "\n"), strings.Split(string(got), "\n")))
+			}
+		})
+	}
+}
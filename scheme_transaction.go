@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+)
+
+// schemeTransaction stages, validates and renames shared schemes over their final path, restoring the old ones if anything fails.
+type schemeTransaction struct {
+	staged []stagedScheme
+	// createdDirs are the scheme directories created while staging, removed on rollback.
+	createdDirs []string
+	// writeRoot confines the staged and committed paths, if not empty.
+	writeRoot string
+	// manifest records the changes of the commit, it may be nil.
+	manifest *fileManifest
+}
+
+type stagedScheme struct {
+	Path       string
+	StagedPath string
+	// BackupPath is a hard link to (or a copy of) the overwritten scheme, created on commit, empty if the scheme is new.
+	BackupPath string
+	committed  bool
+}
+
+// stage writes the scheme to a temporary file in the project's shared scheme directory and validates it.
+func (t *schemeTransaction) stage(projectPath string, scheme generatedScheme) error {
+	contents, err := scheme.Marshal()
+	if err != nil {
+		return err
+	}
+
+	pth := sharedSchemePath(projectPath, scheme.Name)
+	dir := filepath.Dir(pth)
+	// The scheme path is checked as well, the scheme file itself may be a symlink.
+	for _, p := range []string{dir, pth} {
+		if err := checkWritePath(t.writeRoot, p); err != nil {
+			return err
+		}
+	}
+	if err := t.mkdirAll(dir); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// The staged file has no .xcscheme extension, so it is not listed as a scheme.
+	file, err := os.CreateTemp(dir, "."+scheme.Name+".*.staged")
+	if err != nil {
+		return fmt.Errorf("failed to stage Scheme %s: %w", scheme.Name, err)
+	}
+	staged := stagedScheme{
+		Path:       pth,
+		StagedPath: file.Name(),
+	}
+	t.staged = append(t.staged, staged)
+
+	if _, err := file.Write(contents); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stage Scheme %s: %w", scheme.Name, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to stage Scheme %s: %w", scheme.Name, err)
+	}
+
+	if _, err := xcscheme.Open(staged.StagedPath); err != nil {
+		return fmt.Errorf("staged Scheme %s is invalid: %w", scheme.Name, err)
+	}
+
+	return nil
+}
+
+func (t *schemeTransaction) mkdirAll(dir string) error {
+	var missingDirs []string
+	for d := dir; ; d = filepath.Dir(d) {
+		exist, err := pathutil.IsDirExists(d)
+		if err != nil {
+			return err
+		}
+		if exist || d == filepath.Dir(d) {
+			break
+		}
+		missingDirs = append(missingDirs, d)
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	// The deepest directory first, so they can be removed in this order.
+	t.createdDirs = append(t.createdDirs, missingDirs...)
+	return nil
+}
+
+// commit moves the staged schemes into place, and rolls back every change if any of the moves fail.
+func (t *schemeTransaction) commit() error {
+	// Recorded before anything is moved, the parent directories first.
+	for i := len(t.createdDirs) - 1; i >= 0; i-- {
+		t.manifest.recordCreatedDir(t.createdDirs[i])
+	}
+	for _, staged := range t.staged {
+		if err := t.manifest.recordFile(staged.Path); err != nil {
+			return t.rollbackAfter(fmt.Errorf("failed to record %s in the manifest: %w", staged.Path, err))
+		}
+	}
+
+	for i := range t.staged {
+		staged := &t.staged[i]
+
+		if exist, err := pathutil.IsPathExists(staged.Path); err != nil {
+			return t.rollbackAfter(err)
+		} else if exist {
+			backupPath := staged.StagedPath + ".backup"
+			if err := linkOrCopyFile(staged.Path, backupPath); err != nil {
+				return t.rollbackAfter(fmt.Errorf("failed to back up %s: %w", staged.Path, err))
+			}
+			staged.BackupPath = backupPath
+		}
+
+		if err := os.Rename(staged.StagedPath, staged.Path); err != nil {
+			return t.rollbackAfter(fmt.Errorf("failed to write Scheme file (%s): %w", staged.Path, err))
+		}
+		staged.committed = true
+	}
+
+	for _, staged := range t.staged {
+		if staged.BackupPath != "" {
+			if err := os.Remove(staged.BackupPath); err != nil {
+				log.Warnf("Failed to remove the backup of %s: %s", staged.Path, err)
+			}
+		}
+	}
+
+	t.staged = nil
+	t.createdDirs = nil
+	return nil
+}
+
+func (t *schemeTransaction) rollbackAfter(err error) error {
+	if rollbackErr := t.rollback(); rollbackErr != nil {
+		return fmt.Errorf("%w, rolling back the written Schemes failed: %s", err, rollbackErr)
+	}
+	return fmt.Errorf("%w, no Scheme is changed", err)
+}
+
+// rollback removes the staged and committed schemes and restores the overwritten ones.
+func (t *schemeTransaction) rollback() error {
+	var errs []error
+	for i := len(t.staged) - 1; i >= 0; i-- {
+		staged := t.staged[i]
+
+		switch {
+		case staged.committed && staged.BackupPath != "":
+			if err := os.Rename(staged.BackupPath, staged.Path); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore %s from %s: %w", staged.Path, staged.BackupPath, err))
+			}
+			log.Warnf("Rolled back %s", staged.Path)
+		case staged.committed:
+			if err := os.Remove(staged.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+			log.Warnf("Rolled back %s", staged.Path)
+		case staged.BackupPath != "":
+			if err := os.Remove(staged.BackupPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+
+		if err := os.Remove(staged.StagedPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, dir := range t.createdDirs {
+		if err := os.Remove(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	t.staged = nil
+	t.createdDirs = nil
+	return errors.Join(errs...)
+}
+
+// linkOrCopyFile hard links dst to src, or copies it if links are not supported, a symlink is linked itself.
+func linkOrCopyFile(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, dst)
+	}
+	return copyFile(src, dst, info.Mode().Perm())
+}
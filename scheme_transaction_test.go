@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+)
+
+func newTestGeneratedScheme(t *testing.T, name string) generatedScheme {
+	t.Helper()
+	scheme := openTestScheme(t, filepath.Join("testdata", "App-no-tests.xcscheme"))
+	scheme.Name = name
+	return newGeneratedScheme(scheme)
+}
+
+// dirEntries returns the file names in the directory, nil if it does not exist.
+func dirEntries(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readTestFile(t *testing.T, pth string) string {
+	t.Helper()
+	contents, err := os.ReadFile(pth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(contents)
+}
+
+func TestSchemeTransaction(t *testing.T) {
+	tests := []struct {
+		name string
+		// existing are the schemes of App.xcodeproj before the transaction.
+		existing []string
+		schemes  []string
+		// failAt removes the staged file of the scheme with this index before the commit, so its rename fails.
+		failAt      int
+		rollback    bool
+		wantErr     string
+		wantSchemes []string
+	}{
+		{
+			name:        "commit new and overwritten schemes",
+			existing:    []string{"App"},
+			schemes:     []string{"App", "Widget"},
+			failAt:      -1,
+			wantSchemes: []string{"App.xcscheme", "Widget.xcscheme"},
+		},
+		{
+			name:        "commit into a new directory",
+			schemes:     []string{"App"},
+			failAt:      -1,
+			wantSchemes: []string{"App.xcscheme"},
+		},
+		{
+			name:        "rollback after staging",
+			existing:    []string{"App"},
+			schemes:     []string{"App", "Widget"},
+			failAt:      -1,
+			rollback:    true,
+			wantSchemes: []string{"App.xcscheme"},
+		},
+		{
+			name:        "failure partway through the commit",
+			existing:    []string{"App", "Core"},
+			schemes:     []string{"App", "Widget", "Core", "Extension"},
+			failAt:      2,
+			wantErr:     "failed to write Scheme file",
+			wantSchemes: []string{"App.xcscheme", "Core.xcscheme"},
+		},
+		{
+			name:    "failure in a new directory",
+			schemes: []string{"App", "Widget"},
+			failAt:  1,
+			wantErr: "failed to write Scheme file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			projectPath := filepath.Join(root, "App.xcodeproj")
+			if err := os.MkdirAll(projectPath, 0700); err != nil {
+				t.Fatal(err)
+			}
+			schemesDir := filepath.Dir(sharedSchemePath(projectPath, ""))
+			for _, name := range tt.existing {
+				if err := os.MkdirAll(schemesDir, 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(sharedSchemePath(projectPath, name), []byte("original "+name), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			transaction := schemeTransaction{writeRoot: root}
+			for _, name := range tt.schemes {
+				if err := transaction.stage(projectPath, newTestGeneratedScheme(t, name)); err != nil {
+					t.Fatalf("stage(%s) error = %v", name, err)
+				}
+			}
+			if tt.failAt >= 0 {
+				if err := os.Remove(transaction.staged[tt.failAt].StagedPath); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var err error
+			if tt.rollback {
+				err = transaction.rollback()
+			} else {
+				err = transaction.commit()
+			}
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want %s", err, tt.wantErr)
+			}
+
+			// No staged file or backup is left behind.
+			if got := dirEntries(t, schemesDir); !reflect.DeepEqual(got, tt.wantSchemes) {
+				t.Errorf("scheme directory = %v, want %v", got, tt.wantSchemes)
+			}
+			if len(tt.existing) == 0 && len(tt.wantSchemes) == 0 {
+				if got := dirEntries(t, projectPath); len(got) != 0 {
+					t.Errorf("created directories are left behind: %v", got)
+				}
+			}
+
+			committed := err == nil && !tt.rollback
+			for _, name := range tt.existing {
+				got := readTestFile(t, sharedSchemePath(projectPath, name))
+				if overwritten := committed && containsSchemeName(tt.schemes, name); overwritten == (got == "original "+name) {
+					t.Errorf("%s = %q, overwritten: %v", name, got, overwritten)
+				}
+			}
+			if committed {
+				for _, name := range tt.schemes {
+					if _, err := xcscheme.Open(sharedSchemePath(projectPath, name)); err != nil {
+						t.Errorf("committed %s is invalid: %v", name, err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// Package schemelist lists the schemes of Xcode projects and workspaces from the files on disk, the way Xcode shows them.
+package schemelist
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bitrise-io/go-utils/pathutil"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcworkspace"
+)
+
+// Container is an Xcode project or workspace.
+type Container interface {
+	// Schemes returns schemes mapped to the project or workspace path, and the scheme files which could not be parsed
+	Schemes() (map[string][]xcscheme.Scheme, []MalformedScheme, error)
+	// Projects returns the projects of the container and the paths of the missing projects.
+	Projects() ([]xcodeproject.XcodeProj, []string, error)
+}
+
+// projectContainer ...
+type projectContainer struct {
+	project xcodeproject.XcodeProj
+}
+
+func newProject(path string, cache *ProjectCache) (projectContainer, error) {
+	if !xcodeproject.IsXcodeProj(path) {
+		return projectContainer{}, fmt.Errorf("%s is not an Xcode project", path)
+	}
+
+	project, err := cache.Open(path)
+	if err != nil {
+		return projectContainer{}, fmt.Errorf("opening the Xcode project at %s failed: %w", path, err)
+	}
+
+	return projectContainer{
+		project: project,
+	}, nil
+}
+
+func (p projectContainer) Schemes() (map[string][]xcscheme.Scheme, []MalformedScheme, error) {
+	projectSchemes, malformed, err := projectSchemes(p.project, nil)
+	if errors.Is(err, errSchemesNotAutocreated) {
+		return map[string][]xcscheme.Scheme{}, malformed, nil
+	}
+	if err != nil {
+		return nil, malformed, fmt.Errorf("listing schemes in Xcode project at %s failed: %w", p.project.Path, err)
+	}
+
+	containerToSchemes := make(map[string][]xcscheme.Scheme)
+	if len(projectSchemes) > 0 {
+		containerToSchemes[p.project.Path] = projectSchemes
+	}
+
+	return containerToSchemes, malformed, nil
+}
+
+func (p projectContainer) Projects() ([]xcodeproject.XcodeProj, []string, error) {
+	return []xcodeproject.XcodeProj{p.project}, []string{}, nil
+}
+
+// workspaceContainer ...
+type workspaceContainer struct {
+	workspace xcworkspace.Workspace
+	cache     *ProjectCache
+	// excludedProjects are processed with another container, mapped to that container's path.
+	// They are left out of the projects and schemes of the workspace, so they are opened and written once.
+	excludedProjects map[string]string
+}
+
+func newWorkspace(path string, cache *ProjectCache, excludedProjects map[string]string) (workspaceContainer, error) {
+	if !xcworkspace.IsWorkspace(path) {
+		return workspaceContainer{}, fmt.Errorf("%s is not an Xcode workspace", path)
+	}
+
+	workspace, err := xcworkspace.Open(path)
+	if err != nil {
+		return workspaceContainer{}, fmt.Errorf("opening the Xcode workspace at %s failed: %w", path, err)
+	}
+
+	return workspaceContainer{
+		workspace:        workspace,
+		cache:            cache,
+		excludedProjects: excludedProjects,
+	}, nil
+}
+
+func (w workspaceContainer) Schemes() (map[string][]xcscheme.Scheme, []MalformedScheme, error) {
+	containerToSchemes := make(map[string][]xcscheme.Scheme)
+
+	workspaceSchemes, malformed, _, err := LocationSchemes(w.workspace.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing schemes in Xcode workspace at %s failed: %w", w.workspace.Path, err)
+	}
+	if len(workspaceSchemes) > 0 {
+		containerToSchemes[w.workspace.Path] = workspaceSchemes
+	}
+
+	autocreate, err := isWorkspaceAutocreateSchemesEnabled(w.workspace.Path)
+	if err != nil {
+		return nil, malformed, fmt.Errorf("reading the settings of the Xcode workspace at %s failed: %w", w.workspace.Path, err)
+	}
+
+	// Schemes are listed project by project, so a project without schemes, or which can not be opened, does not hide the schemes of the others.
+	var errs []error
+	projects, _, err := w.Projects()
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, project := range projects {
+		schemes, projectMalformed, err := projectSchemes(project, &autocreate)
+		malformed = append(malformed, projectMalformed...)
+		if errors.Is(err, errSchemesNotAutocreated) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listing schemes in Xcode project at %s failed: %w", project.Path, err))
+			continue
+		}
+		if len(schemes) > 0 {
+			containerToSchemes[project.Path] = schemes
+		}
+	}
+
+	return containerToSchemes, malformed, errors.Join(errs...)
+}
+
+// Projects returns the projects of the workspace, the missing project paths and the joined errors of the unopened projects.
+func (w workspaceContainer) Projects() ([]xcodeproject.XcodeProj, []string, error) {
+	projPaths, err := WorkspaceProjectLocations(w.workspace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var projects []xcodeproject.XcodeProj
+	var missingProjects []string
+	var errs []error
+	for _, projPath := range projPaths {
+		if _, excluded := w.excludedProjects[projPath]; excluded {
+			continue
+		}
+
+		if exist, err := pathutil.IsPathExists(projPath); err != nil {
+			errs = append(errs, fmt.Errorf("list Xcode projects in the workspace at %s failed: can not check if path (%s) exists: %w", w.workspace.Path, projPath, err))
+			continue
+		} else if !exist {
+			missingProjects = append(missingProjects, projPath)
+			continue
+		}
+
+		project, err := w.cache.Open(projPath)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("opening the Xcode project (%s) in the workspace at %s failed: %w", projPath, w.workspace.Path, err))
+			continue
+		}
+
+		projects = append(projects, project)
+	}
+
+	return projects, missingProjects, errors.Join(errs...)
+}
+
+// Open opens the project or workspace through the cache (may be nil), leaving the excluded projects out of a workspace.
+func Open(path string, cache *ProjectCache, excludedProjects map[string]string) (Container, error) {
+	if projectPath, ok := EmbeddedWorkspaceProject(path); ok {
+		path = projectPath
+	}
+
+	if xcodeproject.IsXcodeProj(path) {
+		container, err := newProject(path, cache)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open Xcode project: %w", err)
+		}
+
+		return container, nil
+	} else if xcworkspace.IsWorkspace(path) {
+		container, err := newWorkspace(path, cache, excludedProjects)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open Xcode workspace: %w", err)
+		}
+
+		return container, nil
+	}
+
+	return nil, fmt.Errorf("project path (%s) has an invalid extension, excepted '%s' or '%s'",
+		path,
+		xcodeproject.XcodeProjExtension,
+		xcworkspace.XCWorkspaceExtension,
+	)
+}
+
+// ProjectCache keeps the opened projects without reloading them, a nil cache opens a project every time.
+type ProjectCache struct {
+	projects map[string]xcodeproject.XcodeProj
+}
+
+// NewProjectCache ...
+func NewProjectCache() *ProjectCache {
+	return &ProjectCache{projects: map[string]xcodeproject.XcodeProj{}}
+}
+
+// Open returns the project at the path, opening it on the first call.
+func (c *ProjectCache) Open(pth string) (xcodeproject.XcodeProj, error) {
+	if c == nil {
+		return xcodeproject.Open(pth)
+	}
+	if project, ok := c.projects[pth]; ok {
+		return project, nil
+	}
+
+	project, err := xcodeproject.Open(pth)
+	if err != nil {
+		return xcodeproject.XcodeProj{}, err
+	}
+	c.projects[pth] = project
+	return project, nil
+}
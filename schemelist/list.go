@@ -0,0 +1,114 @@
+package schemelist
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// List is the output of `xcodebuild -list -json`, for a project or a workspace.
+type List struct {
+	Project   *ProjectList   `json:"project,omitempty"`
+	Workspace *WorkspaceList `json:"workspace,omitempty"`
+}
+
+// ProjectList ...
+type ProjectList struct {
+	Configurations []string `json:"configurations"`
+	Name           string   `json:"name"`
+	Schemes        []string `json:"schemes"`
+	Targets        []string `json:"targets"`
+}
+
+// WorkspaceList ...
+type WorkspaceList struct {
+	Name    string   `json:"name"`
+	Schemes []string `json:"schemes"`
+}
+
+// ListContainer returns the `xcodebuild -list -json` output of the container computed from the files on disk, the cache may be nil.
+func ListContainer(containerPath string, cache *ProjectCache) (List, error) {
+	container, err := Open(containerPath, cache, nil)
+	if err != nil {
+		return List{}, err
+	}
+
+	containerToSchemes, malformed, err := container.Schemes()
+	if err != nil {
+		return List{}, err
+	}
+
+	var schemes []string
+	for _, containerSchemes := range containerToSchemes {
+		for _, scheme := range containerSchemes {
+			schemes = append(schemes, scheme.Name)
+		}
+	}
+	for _, scheme := range malformed {
+		schemes = append(schemes, scheme.Name())
+	}
+	schemes = sortedSchemeNames(schemes)
+
+	if _, ok := container.(projectContainer); !ok {
+		return List{
+			Workspace: &WorkspaceList{
+				Name:    containerName(containerPath),
+				Schemes: schemes,
+			},
+		}, nil
+	}
+
+	projects, _, err := container.Projects()
+	if err != nil {
+		return List{}, err
+	}
+	project := projects[0]
+
+	list := ProjectList{
+		Configurations: []string{},
+		Name:           containerName(project.Path),
+		Schemes:        schemes,
+		Targets:        []string{},
+	}
+	for _, configuration := range project.Proj.BuildConfigurationList.BuildConfigurations {
+		list.Configurations = append(list.Configurations, configuration.Name)
+	}
+	for _, target := range project.Proj.Targets {
+		list.Targets = append(list.Targets, target.Name)
+	}
+
+	return List{Project: &list}, nil
+}
+
+// sortedSchemeNames returns the unique scheme names, ordered case-insensitively.
+func sortedSchemeNames(names []string) []string {
+	unique := []string{}
+	for _, name := range names {
+		if !containsSchemeName(unique, name) {
+			unique = append(unique, name)
+		}
+	}
+
+	sort.SliceStable(unique, func(i, j int) bool {
+		a, b := strings.ToLower(unique[i]), strings.ToLower(unique[j])
+		if a == b {
+			return unique[i] < unique[j]
+		}
+		return a < b
+	})
+
+	return unique
+}
+
+func containsSchemeName(names []string, name string) bool {
+	for _, n := range names {
+		if IsSameSchemeName(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func containerName(pth string) string {
+	return strings.TrimSuffix(filepath.Base(pth), filepath.Ext(pth))
+}
@@ -0,0 +1,113 @@
+package schemelist
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The ../testdata/xcodebuild_list/<container>.json files are in the format `xcodebuild -list -json <container>` prints.
+func TestListContainer(t *testing.T) {
+	dir := filepath.Join("..", "testdata", "xcodebuild_list")
+
+	tests := []struct {
+		name      string
+		container string
+		wantErr   string
+	}{
+		{
+			name:      "project with shared and malformed schemes",
+			container: "App.xcodeproj",
+		},
+		{
+			name:      "workspace with autocreated project schemes",
+			container: "App.xcworkspace",
+		},
+		{
+			name:      "project without schemes and targets",
+			container: "Empty.xcodeproj",
+		},
+		{
+			name:      "missing container",
+			container: "Missing.xcodeproj",
+			wantErr:   "Missing.xcodeproj",
+		},
+		{
+			name:      "workspace with an unknown location type",
+			container: "Invalid.xcworkspace",
+			wantErr:   "unknown location type (unknown)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ListContainer(filepath.Join(dir, tt.container), nil)
+			checkError(t, err, tt.wantErr)
+			if tt.wantErr != "" {
+				return
+			}
+
+			content, err := os.ReadFile(filepath.Join(dir, tt.container+".json"))
+			if err != nil {
+				t.Fatalf("failed to read the xcodebuild output: %v", err)
+			}
+			var want List
+			if err := json.Unmarshal(content, &want); err != nil {
+				t.Fatalf("failed to parse the xcodebuild output: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.MarshalIndent(got, "", "  ")
+				t.Errorf("ListContainer() = %s\nwant %s", gotJSON, content)
+			}
+		})
+	}
+}
+
+func TestListJSON(t *testing.T) {
+	// Only one of the project and workspace keys is present, like in the xcodebuild output.
+	for _, container := range []string{"App.xcodeproj", "App.xcworkspace"} {
+		content, err := os.ReadFile(filepath.Join("..", "testdata", "xcodebuild_list", container+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var list List
+		if err := json.Unmarshal(content, &list); err != nil {
+			t.Fatal(err)
+		}
+
+		marshalled, err := json.Marshal(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantKey, otherKey := `"project"`, `"workspace"`
+		if strings.HasSuffix(container, ".xcworkspace") {
+			wantKey, otherKey = otherKey, wantKey
+		}
+		if !strings.Contains(string(marshalled), wantKey) || strings.Contains(string(marshalled), otherKey) {
+			t.Errorf("%s: marshalled list = %s, want only the %s key", container, marshalled, wantKey)
+		}
+	}
+}
+
+func TestSortedSchemeNames(t *testing.T) {
+	got := sortedSchemeNames([]string{"beta", "App", "Alpha", "app", "App"})
+	want := []string{"Alpha", "App", "app", "beta"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sortedSchemeNames() = %v, want %v", got, want)
+	}
+}
+
+func checkError(t *testing.T, err error, wantErr string) {
+	t.Helper()
+	switch {
+	case wantErr == "" && err != nil:
+		t.Fatalf("unexpected error: %v", err)
+	case wantErr != "" && err == nil:
+		t.Fatalf("expected error: %s", wantErr)
+	case wantErr != "" && !strings.Contains(err.Error(), wantErr):
+		t.Fatalf("error = %v, want %s", err, wantErr)
+	}
+}
@@ -0,0 +1,150 @@
+package schemelist
+
+import (
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-plist"
+	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-xcode/xcodeproject/serialized"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"golang.org/x/text/unicode/norm"
+)
+
+// IsSameSchemeName compares scheme names the same way as XcodeProj.Scheme and Workspace.Scheme do.
+func IsSameSchemeName(a, b string) bool {
+	return norm.NFC.String(a) == norm.NFC.String(b)
+}
+
+// MalformedScheme is a scheme file which can not be parsed.
+type MalformedScheme struct {
+	Path string
+	Err  error
+}
+
+// Name returns the scheme name, from the file name.
+func (s MalformedScheme) Name() string {
+	return strings.TrimSuffix(filepath.Base(s.Path), filepath.Ext(s.Path))
+}
+
+// LocationSchemes returns the shared and user schemes of a project or workspace, ok is false if it has no scheme files.
+func LocationSchemes(locationPath string) (schemes []xcscheme.Scheme, malformed []MalformedScheme, ok bool, err error) {
+	userDir, err := userSchemesDir(locationPath)
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	for _, dir := range []struct {
+		path   string
+		shared bool
+	}{
+		{filepath.Join(locationPath, "xcshareddata", "xcschemes"), true},
+		{userDir, false},
+	} {
+		entries, err := os.ReadDir(dir.path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, nil, false, err
+		}
+
+		for _, entry := range entries {
+			if filepath.Ext(entry.Name()) != ".xcscheme" {
+				continue
+			}
+			ok = true
+
+			pth := filepath.Join(dir.path, entry.Name())
+			scheme, err := xcscheme.Open(pth)
+			if err != nil {
+				malformed = append(malformed, MalformedScheme{Path: pth, Err: err})
+				continue
+			}
+
+			scheme.IsShared = dir.shared
+			schemes = append(schemes, scheme)
+		}
+	}
+
+	return schemes, malformed, ok, nil
+}
+
+func userSchemesDir(locationPath string) (string, error) {
+	// <project_or_workspace>/xcuserdata/<current_user>.xcuserdatad/xcschemes/
+	currentUser, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(locationPath, "xcuserdata", currentUser.Username+".xcuserdatad", "xcschemes"), nil
+}
+
+// errSchemesNotAutocreated tells that the project has no scheme files, and its default schemes are not autocreated either.
+var errSchemesNotAutocreated = errors.New("no scheme files and 'Autocreate schemes' is disabled")
+
+// projectSchemes returns the schemes and the malformed scheme files of the project, workspaceAutocreate is nil for a lone project.
+func projectSchemes(project xcodeproject.XcodeProj, workspaceAutocreate *bool) ([]xcscheme.Scheme, []MalformedScheme, error) {
+	schemes, malformed, ok, err := LocationSchemes(project.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ok {
+		return schemes, malformed, nil
+	}
+
+	// Without scheme files Xcode shows the default schemes, if the user's scheme management file exists or 'Autocreate schemes' is enabled.
+	autocreate := workspaceAutocreate
+	if autocreate == nil {
+		enabled, err := isWorkspaceAutocreateSchemesEnabled(filepath.Join(project.Path, "project.xcworkspace"))
+		if err != nil {
+			return nil, nil, err
+		}
+		autocreate = &enabled
+	}
+
+	if !*autocreate {
+		userDir, err := userSchemesDir(project.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if exist, err := pathutil.IsPathExists(filepath.Join(userDir, "xcschememanagement.plist")); err != nil {
+			return nil, nil, err
+		} else if !exist {
+			return nil, nil, errSchemesNotAutocreated
+		}
+	}
+
+	schemes, err = project.SchemesWithAutocreateEnabled(true)
+	return schemes, nil, err
+}
+
+// isWorkspaceAutocreateSchemesEnabled returns the 'Autocreate schemes' option of a workspace, or of a project's embedded workspace.
+func isWorkspaceAutocreateSchemesEnabled(workspacePath string) (bool, error) {
+	// <workspace_name>.xcworkspace/xcshareddata/WorkspaceSettings.xcsettings
+	content, err := os.ReadFile(filepath.Join(workspacePath, "xcshareddata", "WorkspaceSettings.xcsettings"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// By default 'Autocreate Schemes' is enabled
+			return true, nil
+		}
+		return false, err
+	}
+
+	var settings serialized.Object
+	if _, err := plist.Unmarshal(content, &settings); err != nil {
+		return false, err
+	}
+
+	autocreate, err := settings.Bool("IDEWorkspaceSharedSettings_AutocreateContextsIfNeeded")
+	if err != nil {
+		if serialized.IsKeyNotFoundError(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return autocreate, nil
+}
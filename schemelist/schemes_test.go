@@ -0,0 +1,41 @@
+package schemelist
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+)
+
+func TestProjectSchemesNotAutocreated(t *testing.T) {
+	project := xcodeproject.XcodeProj{Path: filepath.Join(t.TempDir(), "App.xcodeproj")}
+	settingsDir := filepath.Join(project.Path, "project.xcworkspace", "xcshareddata")
+	if err := os.MkdirAll(settingsDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	settings := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>IDEWorkspaceSharedSettings_AutocreateContextsIfNeeded</key>
+	<false/>
+</dict>
+</plist>
+`
+	if err := os.WriteFile(filepath.Join(settingsDir, "WorkspaceSettings.xcsettings"), []byte(settings), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	schemes, _, err := projectSchemes(project, nil)
+	if !errors.Is(err, errSchemesNotAutocreated) {
+		t.Fatalf("projectSchemes() = %v, %v, want %v", schemes, err, errSchemesNotAutocreated)
+	}
+
+	container := projectContainer{project: project}
+	containerToSchemes, _, err := container.Schemes()
+	if err != nil || len(containerToSchemes) != 0 {
+		t.Fatalf("Schemes() = %v, %v, want no schemes and no error", containerToSchemes, err)
+	}
+}
@@ -0,0 +1,134 @@
+package schemelist
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcworkspace"
+)
+
+const (
+	embeddedWorkspaceName = "project.xcworkspace"
+
+	developerDirEnvKey  = "DEVELOPER_DIR"
+	defaultDeveloperDir = "/Applications/Xcode.app/Contents/Developer"
+)
+
+// EmbeddedWorkspaceProject returns the project of an embedded workspace (<name>.xcodeproj/project.xcworkspace).
+func EmbeddedWorkspaceProject(pth string) (string, bool) {
+	pth = filepath.Clean(pth)
+	if filepath.Base(pth) != embeddedWorkspaceName || !xcodeproject.IsXcodeProj(filepath.Dir(pth)) {
+		return "", false
+	}
+	return filepath.Dir(pth), true
+}
+
+// WorkspaceLocations resolves the "<type>:<path>" locations of a workspace's file references and groups.
+type WorkspaceLocations struct {
+	WorkspacePath string
+	// developerDir is looked up on the first developer location, if not set.
+	developerDir string
+}
+
+// fileLocations returns the absolute paths of the files referenced by the workspace, including the ones in groups.
+func (l *WorkspaceLocations) fileLocations(workspace xcworkspace.Workspace) ([]string, error) {
+	return l.groupFileLocations(workspace.FileRefs, workspace.Groups, filepath.Dir(workspace.Path))
+}
+
+func (l *WorkspaceLocations) groupFileLocations(fileRefs []xcworkspace.FileRef, groups []xcworkspace.Group, groupDir string) ([]string, error) {
+	var locations []string
+	for _, fileRef := range fileRefs {
+		pth, err := l.Resolve(fileRef.Location, groupDir)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, pth)
+	}
+
+	for _, group := range groups {
+		dir, err := l.Resolve(group.Location, groupDir)
+		if err != nil {
+			return nil, err
+		}
+		groupLocations, err := l.groupFileLocations(group.FileRefs, group.Groups, dir)
+		if err != nil {
+			return nil, err
+		}
+		locations = append(locations, groupLocations...)
+	}
+
+	return locations, nil
+}
+
+// Resolve returns the absolute path of the location, relative paths are resolved from the given group directory.
+func (l *WorkspaceLocations) Resolve(location, groupDir string) (string, error) {
+	locationType, pth, found := strings.Cut(location, ":")
+	if !found {
+		return "", fmt.Errorf("unknown location (%s) in the workspace at %s", location, l.WorkspacePath)
+	}
+
+	var base string
+	switch locationType {
+	case "absolute":
+		base = "/"
+	case "group":
+		base = groupDir
+	case "container":
+		base = filepath.Dir(l.WorkspacePath)
+	case "self":
+		if projectPath, ok := EmbeddedWorkspaceProject(l.WorkspacePath); ok {
+			// Older Xcode versions write "self:<name>.xcodeproj", relative to the project's directory.
+			if pth == "" {
+				return projectPath, nil
+			}
+			base = filepath.Dir(projectPath)
+		} else {
+			base = filepath.Dir(l.WorkspacePath)
+		}
+	case "developer":
+		base = l.developerDirectory()
+	default:
+		return "", fmt.Errorf("unknown location type (%s) of %s in the workspace at %s", locationType, location, l.WorkspacePath)
+	}
+
+	if filepath.IsAbs(pth) {
+		return filepath.Clean(pth), nil
+	}
+	return filepath.Join(base, pth), nil
+}
+
+// developerDirectory returns the developer directory of the selected Xcode: DEVELOPER_DIR if set, otherwise the one xcode-select points at.
+func (l *WorkspaceLocations) developerDirectory() string {
+	if l.developerDir != "" {
+		return l.developerDir
+	}
+
+	l.developerDir = defaultDeveloperDir
+	if dir := os.Getenv(developerDirEnvKey); dir != "" {
+		l.developerDir = dir
+	} else if out, err := exec.Command("xcode-select", "--print-path").Output(); err == nil && strings.TrimSpace(string(out)) != "" {
+		l.developerDir = strings.TrimSpace(string(out))
+	}
+	return l.developerDir
+}
+
+// WorkspaceProjectLocations returns the absolute paths of the projects referenced by the workspace.
+func WorkspaceProjectLocations(workspace xcworkspace.Workspace) ([]string, error) {
+	locations := WorkspaceLocations{WorkspacePath: workspace.Path}
+	fileLocations, err := locations.fileLocations(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	var projectLocations []string
+	for _, fileLocation := range fileLocations {
+		if xcodeproject.IsXcodeProj(fileLocation) {
+			projectLocations = append(projectLocations, fileLocation)
+		}
+	}
+	return projectLocations, nil
+}
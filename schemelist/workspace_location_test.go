@@ -0,0 +1,126 @@
+package schemelist
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcworkspace"
+)
+
+// writeTestWorkspace creates a workspace referencing the given locations, the referenced paths are not created.
+func writeTestWorkspace(t *testing.T, pth string, locations ...string) {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<Workspace\n   version = \"1.0\">\n")
+	for _, location := range locations {
+		b.WriteString("   <FileRef\n      location = \"" + location + "\">\n   </FileRef>\n")
+	}
+	b.WriteString("</Workspace>\n")
+
+	if err := os.MkdirAll(pth, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pth, "contents.xcworkspacedata"), []byte(b.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorkspaceLocationsResolve(t *testing.T) {
+	workspace := WorkspaceLocations{WorkspacePath: "/repo/ios/App.xcworkspace", developerDir: "/Xcode.app/Contents/Developer"}
+	embedded := WorkspaceLocations{WorkspacePath: "/repo/ios/App.xcodeproj/project.xcworkspace"}
+
+	tests := []struct {
+		name      string
+		locations WorkspaceLocations
+		location  string
+		groupDir  string
+		want      string
+		wantErr   string
+	}{
+		{name: "absolute", locations: workspace, location: "absolute:/shared/Core.xcodeproj", want: "/shared/Core.xcodeproj"},
+		{name: "group", locations: workspace, location: "group:Core.xcodeproj", groupDir: "/repo/ios/Modules", want: "/repo/ios/Modules/Core.xcodeproj"},
+		{name: "group with parent directory", locations: workspace, location: "group:../shared/Core.xcodeproj", groupDir: "/repo/ios", want: "/repo/shared/Core.xcodeproj"},
+		{name: "container", locations: workspace, location: "container:App.xcodeproj", groupDir: "/repo/ios/Modules", want: "/repo/ios/App.xcodeproj"},
+		{name: "self", locations: workspace, location: "self:App.xcodeproj", want: "/repo/ios/App.xcodeproj"},
+		{name: "self of an embedded workspace", locations: embedded, location: "self:", want: "/repo/ios/App.xcodeproj"},
+		{name: "self of an embedded workspace, older Xcode", locations: embedded, location: "self:App.xcodeproj", want: "/repo/ios/App.xcodeproj"},
+		{name: "developer", locations: workspace, location: "developer:Tools/Core.xcodeproj", want: "/Xcode.app/Contents/Developer/Tools/Core.xcodeproj"},
+		{name: "unknown type", locations: workspace, location: "remote:App.xcodeproj", wantErr: "unknown location type (remote)"},
+		{name: "missing type", locations: workspace, location: "App.xcodeproj", wantErr: "unknown location (App.xcodeproj)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.locations.Resolve(tt.location, tt.groupDir)
+			checkError(t, err, tt.wantErr)
+			if got != tt.want {
+				t.Errorf("Resolve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeveloperDirectory(t *testing.T) {
+	t.Setenv(developerDirEnvKey, "/Applications/Xcode-beta.app/Contents/Developer")
+	locations := WorkspaceLocations{WorkspacePath: "/repo/App.xcworkspace"}
+	got, err := locations.Resolve("developer:Platforms", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "/Applications/Xcode-beta.app/Contents/Developer/Platforms"; got != want {
+		t.Errorf("Resolve() = %s, want %s", got, want)
+	}
+}
+
+func TestEmbeddedWorkspaceProject(t *testing.T) {
+	dir := t.TempDir()
+	projectPath := filepath.Join(dir, "App.xcodeproj")
+
+	tests := []struct {
+		pth       string
+		want      string
+		wantFound bool
+	}{
+		{pth: filepath.Join(projectPath, "project.xcworkspace"), want: projectPath, wantFound: true},
+		{pth: filepath.Join(projectPath, "project.xcworkspace") + "/", want: projectPath, wantFound: true},
+		{pth: filepath.Join(dir, "project.xcworkspace")},
+		{pth: filepath.Join(dir, "App.xcworkspace")},
+	}
+	for _, tt := range tests {
+		got, found := EmbeddedWorkspaceProject(tt.pth)
+		if got != tt.want || found != tt.wantFound {
+			t.Errorf("EmbeddedWorkspaceProject(%s) = %s, %v, want %s, %v", tt.pth, got, found, tt.want, tt.wantFound)
+		}
+	}
+}
+
+func TestWorkspaceProjectLocations(t *testing.T) {
+	dir := t.TempDir()
+	workspacePath := filepath.Join(dir, "ios", "App.xcworkspace")
+	writeTestWorkspace(t, workspacePath,
+		"group:App.xcodeproj",
+		"group:README.md",
+		"container:../shared/Core.xcodeproj",
+		"absolute:"+filepath.Join(dir, "vendor", "Lib.xcodeproj"),
+	)
+
+	workspace, err := xcworkspace.Open(workspacePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := WorkspaceProjectLocations(workspace)
+	if err != nil {
+		t.Fatalf("WorkspaceProjectLocations() error = %v", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "ios", "App.xcodeproj"),
+		filepath.Join(dir, "shared", "Core.xcodeproj"),
+		filepath.Join(dir, "vendor", "Lib.xcodeproj"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WorkspaceProjectLocations() = %v, want %v", got, want)
+	}
+}
@@ -2,31 +2,98 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 
 	"github.com/bitrise-io/go-steputils/v2/stepconf"
 	"github.com/bitrise-io/go-utils/colorstring"
 	"github.com/bitrise-io/go-utils/log"
 	"github.com/bitrise-io/go-utils/pathutil"
+	"github.com/bitrise-io/go-utils/v2/env"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
 	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcworkspace"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+const (
+	generateMode = "generate"
+	diffMode     = "diff"
+	repairMode   = "repair"
+	listMode     = "list"
+	cleanupMode  = "cleanup"
 )
 
 // Input ...
 type Input struct {
-	ProjectPath string `env:"project_path,file"`
+	ProjectPath         string `env:"project_path"`
+	Mode                string `env:"mode,opt[generate,diff,repair,list,cleanup]"`
+	Scheme              string `env:"scheme"`
+	DryRun              bool   `env:"dry_run,opt[yes,no]"`
+	FailOnBrokenSchemes bool   `env:"fail_on_broken_schemes,opt[yes,no]"`
+	Strict              bool   `env:"strict,opt[yes,no]"`
+	MalformedSchemes    string `env:"malformed_schemes,opt[fail,skip,backup_and_regenerate]"`
+	ExecutionActions    string `env:"execution_actions"`
+	SchemeSpecPath      string `env:"scheme_spec_path"`
+	IncludeTargets      string `env:"include_targets"`
+	ExcludeTargets      string `env:"exclude_targets"`
+	DiffFailThreshold   *int   `env:"diff_fail_threshold"`
+	DeployDir           string `env:"deploy_dir"`
+	ManifestPath        string `env:"manifest_path"`
+	OutputDir           string `env:"output_dir"`
+	CopyContainer       bool   `env:"copy_container,opt[yes,no]"`
+	WriteRoot           string `env:"write_root"`
+	OutOfRootProjects   string `env:"out_of_root_projects,opt[fail,skip]"`
+	SwitchToPods        bool   `env:"switch_to_pods_workspace,opt[yes,no]"`
 }
 
 type Config struct {
 	ContainerPath string
+	// ContainerPaths are every container project_path lists, ContainerPath is the one being processed.
+	ContainerPaths []string
+	// ContainerPathCorrected is true if the single container differs from project_path:
+	// it was searched for, resolved from an embedded workspace or switched to the CocoaPods workspace.
+	ContainerPathCorrected bool
+	Mode                   string
+	Scheme                 string
+	DryRun                 bool
+	// FailOnBrokenSchemes fails the step if a shared scheme references missing projects, targets or configurations.
+	FailOnBrokenSchemes bool
+	// Strict fails the step on missing workspace projects, projects which can not be opened or listed, and native targets without a scheme.
+	Strict bool
+	// MalformedSchemes is the policy for the scheme files which can not be parsed: fail, skip or backup_and_regenerate.
+	MalformedSchemes string
+	ExecutionScripts []executionScript
+	SchemeSpec       *schemeSpec
+	TargetFilter     targetFilter
+	// DiffFailThreshold is the number of shared schemes allowed to differ from the generated ones in diff mode, nil means no limit.
+	DiffFailThreshold *int
+	DeployDir         string
+	// Manifest records the files changed by the step, saved to manifest_path or into the write root.
+	Manifest *fileManifest
+	// OutputDir is the directory the generated schemes are saved to, mirroring the source tree, instead of the projects.
+	OutputDir string
+	// CopyContainer copies the container and its projects into the output directory, with the generated schemes.
+	CopyContainer bool
+	// WriteRoot is the directory, with its symlinks resolved, the step writes Schemes and backups into.
+	WriteRoot string
+	// OutOfRootProjects is the policy for the projects outside of the write root: fail or skip.
+	OutOfRootProjects string
+	// ExcludedProjects are the projects of the container processed with an earlier container, mapped to that container's path.
+	ExcludedProjects map[string]string
+	// Projects caches the opened projects for the whole run.
+	Projects *schemelist.ProjectCache
 }
 
 type SchemeGenerator struct {
-	inputParser stepconf.InputParser
+	inputParser   stepconf.InputParser
+	envRepository env.Repository
 }
 
-func NewSchemeGenerator(inputParser stepconf.InputParser) SchemeGenerator {
+func NewSchemeGenerator(inputParser stepconf.InputParser, envRepository env.Repository) SchemeGenerator {
 	return SchemeGenerator{
-		inputParser: inputParser,
+		inputParser:   inputParser,
+		envRepository: envRepository,
 	}
 }
 
@@ -38,42 +105,382 @@ func (g SchemeGenerator) ProcessConfig() (Config, error) {
 	}
 	stepconf.Print(input)
 
-	containerPath, err := pathutil.AbsPath(input.ProjectPath)
+	containerPaths, containerPathCorrected, err := resolveContainerPaths(input.ProjectPath, input.SwitchToPods)
+	if err != nil {
+		return Config{}, err
+	}
+	containerPath := containerPaths[0]
+	if len(containerPaths) > 1 {
+		fmt.Println()
+		log.Printf("Processing %d containers:", len(containerPaths))
+		for _, pth := range containerPaths {
+			log.Printf("- %s", pth)
+		}
+	}
+
+	executionScripts, err := parseExecutionScripts(input.ExecutionActions)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to parse execution actions: %w", err)
+	}
+
+	targetFilter, err := parseTargetFilter(input.IncludeTargets, input.ExcludeTargets)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to parse target filters: %w", err)
+	}
+
+	if input.DiffFailThreshold != nil && *input.DiffFailThreshold < 0 {
+		return Config{}, fmt.Errorf("diff_fail_threshold (%d) can not be negative", *input.DiffFailThreshold)
+	}
+
+	var spec *schemeSpec
+	if input.SchemeSpecPath != "" {
+		s, err := openSchemeSpec(input.SchemeSpecPath)
+		if err != nil {
+			return Config{}, err
+		}
+		spec = &s
+	}
+
+	var outputDir string
+	if input.OutputDir != "" {
+		if outputDir, err = pathutil.AbsPath(input.OutputDir); err != nil {
+			return Config{}, fmt.Errorf("failed to get absolute path for: %s: %w", input.OutputDir, err)
+		}
+		// The schemes changed in place would still be written to the source.
+		if input.Mode == repairMode {
+			return Config{}, fmt.Errorf("output_dir is not supported in repair mode, the Schemes are repaired in place")
+		}
+		if input.MalformedSchemes == malformedSchemesRegenerate {
+			return Config{}, fmt.Errorf("output_dir is not supported with malformed_schemes: %s, the malformed Schemes are moved in place", input.MalformedSchemes)
+		}
+	} else if input.CopyContainer {
+		return Config{}, fmt.Errorf("copy_container requires output_dir")
+	}
+
+	writeRoot := input.WriteRoot
+	if writeRoot == "" {
+		if writeRoot, err = defaultWriteRoot(containersDir(containerPaths), g.envRepository); err != nil {
+			return Config{}, err
+		}
+	}
+	if writeRoot, err = filepath.Abs(writeRoot); err != nil {
+		return Config{}, fmt.Errorf("failed to get absolute path for: %s: %w", writeRoot, err)
+	}
+	if writeRoot, err = filepath.EvalSymlinks(writeRoot); err != nil {
+		return Config{}, fmt.Errorf("failed to resolve the write root: %w", err)
+	}
+
+	manifestPath := input.ManifestPath
+	if manifestPath == "" {
+		manifestPath = defaultManifestPath(writeRoot)
+	}
+	if manifestPath, err = pathutil.AbsPath(manifestPath); err != nil {
+		return Config{}, fmt.Errorf("failed to get absolute path for: %s: %w", input.ManifestPath, err)
+	}
+	manifest, err := openFileManifest(manifestPath)
 	if err != nil {
-		return Config{}, fmt.Errorf("failed to get absolute path for: %s: %w", input.ProjectPath, err)
+		return Config{}, err
 	}
 
 	return Config{
-		ContainerPath: containerPath,
+		ContainerPath:          containerPath,
+		ContainerPaths:         containerPaths,
+		ContainerPathCorrected: containerPathCorrected,
+		Projects:               schemelist.NewProjectCache(),
+		Mode:                   input.Mode,
+		Scheme:                 input.Scheme,
+		DryRun:                 input.DryRun,
+		FailOnBrokenSchemes:    input.FailOnBrokenSchemes,
+		Strict:                 input.Strict,
+		MalformedSchemes:       input.MalformedSchemes,
+		ExecutionScripts:       executionScripts,
+		SchemeSpec:             spec,
+		TargetFilter:           targetFilter,
+		DiffFailThreshold:      input.DiffFailThreshold,
+		DeployDir:              input.DeployDir,
+		Manifest:               manifest,
+		OutputDir:              outputDir,
+		CopyContainer:          input.CopyContainer,
+		WriteRoot:              writeRoot,
+		OutOfRootProjects:      input.OutOfRootProjects,
 	}, nil
 }
 
-func (g SchemeGenerator) Run(cfg Config) error {
-	container, err := openContainer(cfg.ContainerPath)
+// resolveContainerPath returns the absolute container path, and whether it was searched for in a directory.
+func resolveContainerPath(projectPath string) (string, bool, error) {
+	if projectPath == "" {
+		projectPath = "."
+	}
+
+	containerPath, err := pathutil.AbsPath(projectPath)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get absolute path for: %s: %w", projectPath, err)
+	}
+
+	info, err := os.Stat(containerPath)
+	if err != nil {
+		return "", false, fmt.Errorf("project_path (%s) does not exist: %w", projectPath, err)
+	}
+	if info.IsDir() && !xcodeproject.IsXcodeProj(containerPath) && !xcworkspace.IsWorkspace(containerPath) {
+		containerPath, err := discoverContainer(containerPath)
+		return containerPath, err == nil, err
+	}
+
+	return containerPath, false, nil
+}
+
+// Run runs the configured mode for every container, and saves the manifest of the changed files, even if the mode fails after changing files.
+func (g SchemeGenerator) Run(cfg Config) (Result, error) {
+	result, err := g.runContainers(cfg)
+	if saveErr := cfg.Manifest.save(); saveErr != nil {
+		if err != nil {
+			return result, fmt.Errorf("%w, and saving the manifest failed: %s", err, saveErr)
+		}
+		return Result{}, saveErr
+	}
+	return result, err
+}
+
+func (g SchemeGenerator) runContainers(cfg Config) (Result, error) {
+	if cfg.Mode == cleanupMode {
+		// The manifest covers every container, it is restored once.
+		if err := g.cleanup(cfg); err != nil {
+			return Result{}, err
+		}
+	}
+
+	if len(cfg.ContainerPaths) > 1 {
+		return g.runMultiple(cfg)
+	}
+	return g.run(cfg)
+}
+
+func (g SchemeGenerator) run(cfg Config) (Result, error) {
+	container, err := openConfiguredContainer(cfg)
 	if err != nil {
-		return fmt.Errorf("opening container failed: %w", err)
+		return Result{}, fmt.Errorf("opening container failed: %w", err)
 	}
 
+	var result Result
+
 	fmt.Println()
 	log.Infof("Collecting existing Schemes...")
-	containerToSchemes, err := container.schemes()
-	if err != nil {
-		log.Warnf("Failed to list schemes: %s", err)
+	containerToSchemes, malformedSchemes, listErr := container.Schemes()
+	if listErr != nil {
+		log.Warnf("Failed to list schemes: %s", listErr)
+		result.Warnings = append(result.Warnings, fmt.Sprintf("failed to list schemes: %s", listErr))
 	}
 
-	if len(containerToSchemes) > 0 {
-		log.Printf("Schemes:")
-		printSchemes(true, containerToSchemes, cfg.ContainerPath)
+	if cfg.Mode == listMode {
+		// Listing is read-only, the malformed schemes are listed by their file name.
+		result.ContainerToSchemes = containerToSchemes
+		list, err := g.list(cfg)
+		result.List = &list
+		return result, err
+	}
+	if cfg.Mode == cleanupMode {
+		// The outputs describe the restored tree.
+		result.ContainerToSchemes = containerToSchemes
+		return result, nil
+	}
+
+	printContainerSchemes(containerToSchemes, cfg.ContainerPath)
+
+	// The problems of strict mode are collected before the first write, and reported at once.
+	var problems strictProblems
+	if cfg.Strict {
+		problems = containerProblems(cfg, container, listErr)
+	}
+
+	if cfg.Mode != diffMode {
+		log.Printf("Writes are confined to: %s", cfg.WriteRoot)
+		warnings, err := g.confineWrites(cfg, container)
+		if err != nil && !cfg.Strict {
+			return Result{}, err
+		}
+		problems.addError(err)
+		result.Warnings = append(result.Warnings, warnings...)
+	}
+
+	for _, scheme := range malformedSchemes {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("malformed scheme %s: %s", pathRelativeToWorkspace(scheme.Path, cfg.ContainerPath), scheme.Err))
+	}
+	// The malformed schemes left untouched are not overwritten by the generated schemes.
+	var skippedSchemes []schemelist.MalformedScheme
+	if cfg.MalformedSchemes == malformedSchemesSkip {
+		skippedSchemes = malformedSchemes
+	}
+	var regenerations []schemeRegeneration
+	if len(malformedSchemes) > 0 {
+		regenerations, err = g.planMalformedSchemes(cfg, container, malformedSchemes)
+		if err != nil && !cfg.Strict {
+			return Result{}, err
+		}
+		problems.addError(err)
+	}
+	projectToRegenerated := regeneratedSchemes(regenerations)
+
+	// The requested scheme, or without shared schemes every scheme, is planned before the first write.
+	sharedSchemes := numberOfSharedSchemes(containerToSchemes)
+	for _, schemes := range projectToRegenerated {
+		sharedSchemes += len(schemes)
+	}
+	var requested requestedScheme
+	if cfg.Mode == generateMode && cfg.Scheme != "" {
+		requested, err = g.planRequestedScheme(cfg, container, containerToSchemes, projectToRegenerated, skippedSchemes)
+		if err != nil && !cfg.Strict {
+			return Result{}, err
+		}
+		problems.addError(err)
+	}
+
+	var projects []xcodeproject.XcodeProj
+	var projectToSchemes map[string][]generatedScheme
+	if cfg.Mode == generateMode && cfg.Scheme == "" {
+		if sharedSchemes == 0 {
+			projects, projectToSchemes, result.FilteredTargets, err = g.planSchemes(cfg, container, skippedSchemes)
+			if err != nil {
+				return Result{}, err
+			}
+		} else if cfg.Strict {
+			projects, _, _ = container.Projects()
+			projects = projectsInWriteRoot(cfg, projects)
+		}
+
+		if cfg.Strict {
+			problems = append(problems, targetsWithoutScheme(cfg, projects, containerToSchemes, mergeGeneratedSchemes(projectToRegenerated, projectToSchemes))...)
+		}
+	}
 
-		preexistingSharedSchemes := numberOfSharedSchemes(containerToSchemes)
-		if preexistingSharedSchemes > 0 {
+	if err := problems.err(); err != nil {
+		fmt.Println()
+		return Result{}, err
+	}
+
+	if len(regenerations) > 0 {
+		regenerated, regeneratedPaths, err := g.regenerateMalformedSchemes(cfg, regenerations)
+		if err != nil {
+			return Result{}, err
+		}
+		result.GeneratedSchemes = regenerated
+		result.RegeneratedSchemes = regenerated
+		result.GeneratedSchemePaths = regeneratedPaths
+		result.RegeneratedSchemePaths = regeneratedPaths
+
+		if len(regenerated) > 0 {
 			fmt.Println()
-			log.Donef("There are %d shared Scheme(s).", preexistingSharedSchemes)
-			return nil
+			log.Infof("Collecting the Schemes again...")
+			containerToSchemes, _, err = container.Schemes()
+			if err != nil {
+				log.Warnf("Failed to list schemes: %s", err)
+			}
+			printContainerSchemes(containerToSchemes, cfg.ContainerPath)
+		}
+	}
+
+	result.ContainerToSchemes = containerToSchemes
+
+	if cfg.Mode == repairMode {
+		// Repair runs before linting, the broken references it fixes should not fail the step.
+		return result, g.repair(cfg, container, containerToSchemes)
+	}
+
+	issues, err := g.lintSchemes(cfg, container, containerToSchemes)
+	if err != nil {
+		return Result{}, err
+	}
+	for _, issue := range issues {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("broken reference in %s, %s: %s", pathRelativeToWorkspace(issue.SchemePath, cfg.ContainerPath), issue.Element, issue.Reason))
+	}
+
+	if cfg.Mode == diffMode {
+		return result, g.diff(cfg, container, containerToSchemes)
+	}
+
+	if cfg.Scheme != "" {
+		ensured, err := g.ensureScheme(cfg, container, requested)
+		if err != nil {
+			return Result{}, err
 		}
+		result.GeneratedSchemes = append(result.GeneratedSchemes, ensured.GeneratedSchemes...)
+		result.GeneratedSchemePaths = append(result.GeneratedSchemePaths, ensured.GeneratedSchemePaths...)
+		result.PrimaryScheme = ensured.PrimaryScheme
+		if ensured.ContainerToSchemes != nil {
+			result.ContainerToSchemes = ensured.ContainerToSchemes
+		}
+		result.ContainerCopyPath = ensured.ContainerCopyPath
+		return result, nil
+	}
+
+	if sharedSchemes > 0 {
+		fmt.Println()
+		log.Donef("There are %d shared Scheme(s).", sharedSchemes)
+
+		if len(result.RegeneratedSchemes) > 0 {
+			projects, _, _ := container.Projects()
+			result.PrimaryScheme = primaryScheme(cfg.ContainerPath, containerToSchemes, result.RegeneratedSchemes, projects)
+		}
+		return result, nil
+	}
+
+	if cfg.DryRun {
+		unlistedReason := "no Scheme generated"
+		if cfg.SchemeSpec != nil {
+			unlistedReason = "not a build target in the scheme spec"
+		}
+		return result, printGenerationPlan(cfg, projects, projectToSchemes, unlistedReason)
+	}
+
+	var projectPaths []string
+	for _, project := range projects {
+		projectPaths = append(projectPaths, project.Path)
+	}
+	output := newSchemeOutput(cfg, projects)
+	if err := output.save(projectPaths, projectToSchemes, cfg.Manifest); err != nil {
+		return Result{}, err
+	}
+	result.ContainerCopyPath = output.containerCopyPath()
+	for _, projectPath := range projectPaths {
+		for _, scheme := range projectToSchemes[projectPath] {
+			result.GeneratedSchemes = append(result.GeneratedSchemes, scheme.Name)
+			result.GeneratedSchemePaths = append(result.GeneratedSchemePaths, output.path(sharedSchemePath(projectPath, scheme.Name)))
+		}
+	}
+
+	container, err = openConfiguredContainer(cfg)
+	if err != nil {
+		return Result{}, fmt.Errorf("opening the updated container failed: %w", err)
+	}
+	containerToSchemesNew, _, err := container.Schemes()
+	if err != nil {
+		return Result{}, fmt.Errorf("getting new schemes failed: %w", err)
+	}
+	if containerToSchemesNew, err = output.schemes(containerToSchemesNew, projectPaths); err != nil {
+		return Result{}, fmt.Errorf("getting new schemes failed: %w", err)
+	}
+
+	result.ContainerToSchemes = containerToSchemesNew
+	numberOfNewSchemes := numberOfSharedSchemes(containerToSchemesNew)
+
+	if numberOfNewSchemes == 0 {
+		fmt.Println()
+		return Result{}, fmt.Errorf("no schemes generated")
 	}
 
-	// Generate schemes
+	fmt.Println()
+	log.Printf("Created Schemes:")
+	printSchemes(false, containerToSchemesNew, cfg.ContainerPath)
+
+	fmt.Println()
+	log.Donef("Generated %d shared Scheme(s).", numberOfNewSchemes)
+
+	result.PrimaryScheme = primaryScheme(cfg.ContainerPath, containerToSchemesNew, result.GeneratedSchemes, projects)
+
+	return result, nil
+}
+
+// planSchemes returns the projects, their schemes with the execution actions and the filtered targets, without writing.
+func (g SchemeGenerator) planSchemes(cfg Config, container schemelist.Container, skippedSchemes []schemelist.MalformedScheme) ([]xcodeproject.XcodeProj, map[string][]generatedScheme, []filteredTarget, error) {
 	fmt.Println()
 	log.Warnf("No shared Schemes found...")
 	log.Warnf("The newly generated Schemes may differ from the ones in your Project.")
@@ -82,50 +489,160 @@ func (g SchemeGenerator) Run(cfg Config) error {
 	fmt.Println()
 	log.Infof("Generating Schemes...")
 
-	projects, missingProjects, err := container.projects()
-	if err != nil {
-		return fmt.Errorf("getting projects failed: %w", err)
+	projects, missingProjects, err := container.Projects()
+	if err != nil && !cfg.Strict {
+		return nil, nil, nil, fmt.Errorf("getting projects failed: %w", err)
 	}
 
 	for _, missingProject := range missingProjects {
 		log.Warnf("Skipping project (%s), as it is not present", pathRelativeToWorkspace(missingProject, cfg.ContainerPath))
 	}
 
-	for _, project := range projects {
-		log.Printf("Recreating Schemes for: %s", filepath.Base(project.Path))
-		schemes := project.ReCreateSchemes()
+	projects = projectsInWriteRoot(cfg, projects)
+	projectToSchemes, filteredTargets, err := generateSchemes(cfg, projects)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("generating schemes failed: %w", err)
+	}
+	projectToSchemes = withoutSkippedSchemes(projectToSchemes, skippedSchemes, cfg.ContainerPath)
+
+	for projectPath, schemes := range projectToSchemes {
+		for i, scheme := range schemes {
+			schemes[i] = addExecutionScripts(scheme, cfg.ExecutionScripts)
+		}
+		projectToSchemes[projectPath] = schemes
+	}
+
+	return projects, projectToSchemes, filteredTargets, nil
+}
+
+func mergeGeneratedSchemes(a, b map[string][]generatedScheme) map[string][]generatedScheme {
+	merged := map[string][]generatedScheme{}
+	for _, projectToSchemes := range []map[string][]generatedScheme{a, b} {
+		for projectPath, schemes := range projectToSchemes {
+			merged[projectPath] = append(merged[projectPath], schemes...)
+		}
+	}
+	return merged
+}
+
+// requestedScheme is the scheme requested by the scheme input, Generated is nil if it is shared or regenerated from a malformed scheme file.
+type requestedScheme struct {
+	Name        string
+	Generated   *generatedScheme
+	ProjectPath string
+	Projects    []xcodeproject.XcodeProj
+}
 
+// planRequestedScheme finds the requested scheme, or generates it without saving, but not over a skipped malformed scheme file.
+func (g SchemeGenerator) planRequestedScheme(cfg Config, container schemelist.Container, containerToSchemes map[string][]xcscheme.Scheme, projectToRegenerated map[string][]generatedScheme, skippedSchemes []schemelist.MalformedScheme) (requestedScheme, error) {
+	if scheme, schemeContainer, ok := findSharedScheme(containerToSchemes, cfg.Scheme); ok {
+		fmt.Println()
+		log.Donef("Scheme %s is shared in %s.", cfg.Scheme, pathRelativeToWorkspace(schemeContainer, cfg.ContainerPath))
+		return requestedScheme{Name: scheme.Name}, nil
+	}
+	for projectPath, schemes := range projectToRegenerated {
 		for _, scheme := range schemes {
-			if err := project.SaveSharedScheme(scheme); err != nil {
-				return fmt.Errorf("saving scheme %s failed: %w", scheme.Name, err)
+			if schemelist.IsSameSchemeName(scheme.Name, cfg.Scheme) {
+				fmt.Println()
+				log.Donef("Scheme %s is regenerated in %s.", cfg.Scheme, pathRelativeToWorkspace(projectPath, cfg.ContainerPath))
+				return requestedScheme{Name: scheme.Name}, nil
 			}
 		}
 	}
 
-	container, err = openContainer(cfg.ContainerPath)
+	fmt.Println()
+	log.Warnf("Scheme %s is not shared...", cfg.Scheme)
+
+	fmt.Println()
+	log.Infof("Generating Scheme %s...", cfg.Scheme)
+
+	projects, missingProjects, err := container.Projects()
 	if err != nil {
-		return fmt.Errorf("opening the updated container failed: %w", err)
+		return requestedScheme{}, fmt.Errorf("getting projects failed: %w", err)
+	}
+
+	for _, missingProject := range missingProjects {
+		log.Warnf("Skipping project (%s), as it is not present", pathRelativeToWorkspace(missingProject, cfg.ContainerPath))
 	}
-	containerToSchemesNew, err := container.schemes()
+
+	projects = projectsInWriteRoot(cfg, projects)
+	scheme, projectPath, found, err := generateRequestedScheme(cfg, projects, cfg.Scheme)
 	if err != nil {
-		return fmt.Errorf("getting new schemes failed: %w", err)
+		return requestedScheme{}, fmt.Errorf("generating scheme %s failed: %w", cfg.Scheme, err)
+	}
+	if !found {
+		return requestedScheme{}, newSchemeNotFoundError(cfg.Scheme, projects, containerToSchemes)
+	}
+	if conflict, ok := skippedSchemeConflict(skippedSchemes, projectPath, scheme.Name); ok {
+		return requestedScheme{}, fmt.Errorf("scheme %s would overwrite the malformed Scheme file %s, fix the file or set malformed_schemes to %s", scheme.Name, pathRelativeToWorkspace(conflict.Path, cfg.ContainerPath), malformedSchemesRegenerate)
 	}
 
-	numberOfNewSchemes := numberOfSharedSchemes(containerToSchemesNew)
+	scheme = addExecutionScripts(scheme, cfg.ExecutionScripts)
+	return requestedScheme{Name: scheme.Name, Generated: &scheme, ProjectPath: projectPath, Projects: projects}, nil
+}
 
-	if numberOfNewSchemes == 0 {
-		fmt.Println()
-		return fmt.Errorf("no schemes generated")
+// ensureScheme saves the planned requested scheme if it is not shared.
+func (g SchemeGenerator) ensureScheme(cfg Config, container schemelist.Container, requested requestedScheme) (Result, error) {
+	if requested.Generated == nil {
+		return Result{PrimaryScheme: requested.Name}, nil
 	}
 
-	fmt.Println()
-	log.Printf("Created Schemes:")
-	printSchemes(false, containerToSchemesNew, cfg.ContainerPath)
+	scheme, projectPath, projects := *requested.Generated, requested.ProjectPath, requested.Projects
+	if cfg.DryRun {
+		return Result{}, printGenerationPlan(cfg, projects, map[string][]generatedScheme{projectPath: {scheme}}, "not the requested Scheme")
+	}
+
+	output := newSchemeOutput(cfg, projects)
+	if err := output.save([]string{projectPath}, map[string][]generatedScheme{projectPath: {scheme}}, cfg.Manifest); err != nil {
+		return Result{}, fmt.Errorf("saving scheme %s failed: %w", scheme.Name, err)
+	}
 
 	fmt.Println()
-	log.Donef("Generated %d shared Scheme(s).", numberOfNewSchemes)
+	log.Donef("Generated Scheme %s in %s.", scheme.Name, pathRelativeToWorkspace(projectPath, cfg.ContainerPath))
 
-	return nil
+	result := Result{
+		GeneratedSchemes:     []string{scheme.Name},
+		GeneratedSchemePaths: []string{output.path(sharedSchemePath(projectPath, scheme.Name))},
+		PrimaryScheme:        scheme.Name,
+		ContainerCopyPath:    output.containerCopyPath(),
+	}
+	containerToSchemesNew, _, err := container.Schemes()
+	if err == nil {
+		containerToSchemesNew, err = output.schemes(containerToSchemesNew, []string{projectPath})
+	}
+	if err != nil {
+		log.Warnf("Failed to list schemes: %s", err)
+	}
+	result.ContainerToSchemes = containerToSchemesNew
+
+	return result, nil
+}
+
+// generateSchemes returns the schemes of the spec, or the default schemes of the unfiltered targets, and the filtered targets.
+func generateSchemes(cfg Config, projects []xcodeproject.XcodeProj) (map[string][]generatedScheme, []filteredTarget, error) {
+	if cfg.SchemeSpec != nil {
+		log.Printf("Rendering Schemes from the scheme spec")
+		projectToSchemes, err := cfg.SchemeSpec.render(projects, cfg.ContainerPath)
+		return projectToSchemes, nil, err
+	}
+
+	projectToSchemes := map[string][]generatedScheme{}
+	var filteredTargets []filteredTarget
+	for _, project := range projects {
+		log.Printf("Recreating Schemes for: %s", filepath.Base(project.Path))
+		var schemes []generatedScheme
+		for _, scheme := range recreateSchemes(project) {
+			schemes = append(schemes, newGeneratedScheme(scheme))
+		}
+
+		schemes, filtered := cfg.TargetFilter.filterSchemes(project, schemes)
+		projectToSchemes[project.Path] = schemes
+		filteredTargets = append(filteredTargets, filtered...)
+	}
+
+	printFilteredTargets(filteredTargets, cfg.ContainerPath)
+
+	return projectToSchemes, filteredTargets, nil
 }
 
 func pathRelativeToWorkspace(project, workspace string) string {
@@ -152,10 +669,17 @@ func numberOfSharedSchemes(containerToSchemes map[string][]xcscheme.Scheme) int
 	return count
 }
 
+func printContainerSchemes(containerToSchemes map[string][]xcscheme.Scheme, containerPath string) {
+	if len(containerToSchemes) > 0 {
+		log.Printf("Schemes:")
+		printSchemes(true, containerToSchemes, containerPath)
+	}
+}
+
 func printSchemes(includeUserSchemes bool, containerToSchemes map[string][]xcscheme.Scheme, containerPath string) {
-	for container, schemes := range containerToSchemes {
+	for _, container := range sortedContainerPaths(containerToSchemes) {
 		log.Printf("- %s", pathRelativeToWorkspace(container, containerPath))
-		for _, scheme := range schemes {
+		for _, scheme := range containerToSchemes[container] {
 			if scheme.IsShared {
 				log.Printf("  - %s (Shared)", scheme.Name)
 			} else if includeUserSchemes {
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-io/go-xcode/xcodeproject/xcscheme"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+// strictProblems collects the problems strict mode fails on, so that every problem is reported at once.
+type strictProblems []string
+
+func (p *strictProblems) add(problem string) {
+	for _, existing := range *p {
+		if existing == problem {
+			return
+		}
+	}
+	*p = append(*p, problem)
+}
+
+// addError adds the errors joined in err one by one.
+func (p *strictProblems) addError(err error) {
+	if err == nil {
+		return
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, e := range joined.Unwrap() {
+			p.addError(e)
+		}
+		return
+	}
+	p.add(err.Error())
+}
+
+// err returns the error listing every problem, nil if there is none.
+func (p strictProblems) err() error {
+	if len(p) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "strict mode, %d problem(s) found:", len(p))
+	for _, problem := range p {
+		fmt.Fprintf(&b, "\n- %s", problem)
+	}
+	return errors.New(b.String())
+}
+
+// containerProblems returns the missing workspace references, the projects which can not be opened and the scheme listing errors.
+func containerProblems(cfg Config, container schemelist.Container, listErr error) strictProblems {
+	var problems strictProblems
+
+	_, missingProjects, err := container.Projects()
+	for _, missingProject := range missingProjects {
+		problems.add(fmt.Sprintf("project %s is referenced by the workspace, but it is not present", pathRelativeToWorkspace(missingProject, cfg.ContainerPath)))
+	}
+	problems.addError(err)
+	problems.addError(listErr)
+
+	return problems
+}
+
+// targetsWithoutScheme returns a problem for every unfiltered native, non-test target which no shared or generated scheme builds.
+func targetsWithoutScheme(cfg Config, projects []xcodeproject.XcodeProj, containerToSchemes map[string][]xcscheme.Scheme, projectToGenerated map[string][]generatedScheme) strictProblems {
+	if cfg.SchemeSpec != nil {
+		return nil
+	}
+
+	built := map[string]bool{}
+	addBuilt := func(schemeContainerPath string, entries []xcscheme.BuildActionEntry) {
+		for _, entry := range entries {
+			projectPath, err := entry.BuildableReference.ReferencedContainerAbsPath(filepath.Dir(schemeContainerPath))
+			if err != nil {
+				continue
+			}
+			built[projectPath+":"+entry.BuildableReference.BlueprintIdentifier] = true
+		}
+	}
+	for containerPath, schemes := range containerToSchemes {
+		for _, scheme := range schemes {
+			if scheme.IsShared {
+				addBuilt(containerPath, scheme.BuildAction.BuildActionEntries)
+			}
+		}
+	}
+	for projectPath, schemes := range projectToGenerated {
+		for _, scheme := range schemes {
+			addBuilt(projectPath, scheme.BuildAction.BuildActionEntries)
+		}
+	}
+
+	var problems strictProblems
+	for _, project := range projects {
+		for _, target := range project.Proj.Targets {
+			if target.Type != xcodeproject.NativeTargetType || target.IsTest() || cfg.TargetFilter.filter(target, project.Path) != "" {
+				continue
+			}
+			if !built[project.Path+":"+target.ID] {
+				problems.add(fmt.Sprintf("target %s in %s has no shared Scheme", target.Name, pathRelativeToWorkspace(project.Path, cfg.ContainerPath)))
+			}
+		}
+	}
+	return problems
+}
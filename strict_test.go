@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+func TestRunStrictFailsBeforeWriting(t *testing.T) {
+	root := t.TempDir()
+	if err := copyDir(filepath.Join("testdata", "xcodebuild_list"), root); err != nil {
+		t.Fatal(err)
+	}
+
+	// A missing workspace project, and a malformed scheme the backup_and_regenerate policy would replace.
+	workspacePath := filepath.Join(root, "App.xcworkspace")
+	contentsPath := filepath.Join(workspacePath, "contents.xcworkspacedata")
+	contents, err := os.ReadFile(contentsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents = []byte(strings.Replace(string(contents), "</Workspace>", "   <FileRef\n      location = \"group:Missing/Missing.xcodeproj\">\n   </FileRef>\n</Workspace>", 1))
+	if err := os.WriteFile(contentsPath, contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+	schemePath := sharedSchemePath(filepath.Join(root, "App.xcodeproj"), "App")
+	if err := os.MkdirAll(filepath.Dir(schemePath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(schemePath, []byte("malformed"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := openFileManifest(defaultManifestPath(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		ContainerPath:     workspacePath,
+		Mode:              generateMode,
+		Strict:            true,
+		MalformedSchemes:  malformedSchemesRegenerate,
+		WriteRoot:         root,
+		OutOfRootProjects: outOfRootProjectsSkip,
+		Manifest:          manifest,
+		Projects:          schemelist.NewProjectCache(),
+	}
+	_, err = (SchemeGenerator{}).run(cfg)
+	if err == nil || !strings.Contains(err.Error(), "Missing/Missing.xcodeproj") || !strings.Contains(err.Error(), "target AppWidget") {
+		t.Fatalf("run() error = %v, want the missing project and the targets without a scheme", err)
+	}
+
+	if content, err := os.ReadFile(schemePath); err != nil || string(content) != "malformed" {
+		t.Errorf("malformed scheme = %q, %v, want it untouched", content, err)
+	}
+	if _, err := os.Stat(schemePath + ".bak"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("malformed scheme is backed up: %v", err)
+	}
+}
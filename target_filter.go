@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+)
+
+const regexPatternPrefix = "regex:"
+
+// targetPattern matches a target's name, product type or project file name with a glob, or a 'regex:' prefixed regular expression.
+type targetPattern struct {
+	raw   string
+	regex *regexp.Regexp
+}
+
+func newTargetPattern(raw string) (targetPattern, error) {
+	if expr, ok := strings.CutPrefix(raw, regexPatternPrefix); ok {
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			return targetPattern{}, fmt.Errorf("invalid regex pattern (%s): %w", raw, err)
+		}
+		return targetPattern{raw: raw, regex: regex}, nil
+	}
+
+	if _, err := path.Match(raw, ""); err != nil {
+		return targetPattern{}, fmt.Errorf("invalid glob pattern (%s): %w", raw, err)
+	}
+
+	return targetPattern{raw: raw}, nil
+}
+
+func (p targetPattern) match(target xcodeproject.Target, projectPath string) bool {
+	for _, value := range []string{target.Name, target.ProductType, filepath.Base(projectPath)} {
+		if value == "" {
+			continue
+		}
+
+		if p.regex != nil {
+			if p.regex.MatchString(value) {
+				return true
+			}
+		} else if matched, _ := path.Match(p.raw, value); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// targetFilter keeps the targets matching an include pattern (if any), then removes the ones matching an exclude pattern.
+type targetFilter struct {
+	include []targetPattern
+	exclude []targetPattern
+}
+
+// filteredTarget is a target removed by a targetFilter.
+type filteredTarget struct {
+	Target      xcodeproject.Target
+	ProjectPath string
+	// Filter is the input and pattern responsible for removing the target.
+	Filter string
+}
+
+func parseTargetFilter(include, exclude string) (targetFilter, error) {
+	var filter targetFilter
+	for _, patterns := range []struct {
+		input  string
+		parsed *[]targetPattern
+	}{
+		{include, &filter.include},
+		{exclude, &filter.exclude},
+	} {
+		for _, line := range strings.Split(patterns.input, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			pattern, err := newTargetPattern(line)
+			if err != nil {
+				return targetFilter{}, err
+			}
+			*patterns.parsed = append(*patterns.parsed, pattern)
+		}
+	}
+
+	return filter, nil
+}
+
+func (f targetFilter) isEmpty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+// filter returns the reason for removing the target, or an empty string if the target is kept.
+func (f targetFilter) filter(target xcodeproject.Target, projectPath string) string {
+	if len(f.include) > 0 {
+		included := false
+		for _, pattern := range f.include {
+			if pattern.match(target, projectPath) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return "include_targets (no matching pattern)"
+		}
+	}
+
+	for _, pattern := range f.exclude {
+		if pattern.match(target, projectPath) {
+			return fmt.Sprintf("exclude_targets (%s)", pattern.raw)
+		}
+	}
+
+	return ""
+}
+
+// filterSchemes removes the schemes whose main build target is filtered out.
+func (f targetFilter) filterSchemes(project xcodeproject.XcodeProj, schemes []generatedScheme) ([]generatedScheme, []filteredTarget) {
+	if f.isEmpty() {
+		return schemes, nil
+	}
+
+	var kept []generatedScheme
+	var removed []filteredTarget
+	for _, scheme := range schemes {
+		reference, ok := scheme.buildableReference()
+		if !ok {
+			kept = append(kept, scheme)
+			continue
+		}
+
+		target, ok := project.Proj.Target(reference.BlueprintIdentifier)
+		if !ok {
+			kept = append(kept, scheme)
+			continue
+		}
+
+		if reason := f.filter(target, project.Path); reason != "" {
+			removed = append(removed, filteredTarget{Target: target, ProjectPath: project.Path, Filter: reason})
+			continue
+		}
+
+		kept = append(kept, scheme)
+	}
+
+	return kept, removed
+}
+
+// groupFilteredTargets returns the filters in the order they first removed a target, and the targets removed by each filter.
+func groupFilteredTargets(filteredTargets []filteredTarget) ([]string, map[string][]filteredTarget) {
+	var filters []string
+	filterToTargets := map[string][]filteredTarget{}
+	for _, filtered := range filteredTargets {
+		if _, ok := filterToTargets[filtered.Filter]; !ok {
+			filters = append(filters, filtered.Filter)
+		}
+		filterToTargets[filtered.Filter] = append(filterToTargets[filtered.Filter], filtered)
+	}
+	return filters, filterToTargets
+}
+
+func printFilteredTargets(filteredTargets []filteredTarget, containerPath string) {
+	if len(filteredTargets) == 0 {
+		return
+	}
+
+	filters, filterToTargets := groupFilteredTargets(filteredTargets)
+
+	fmt.Println()
+	log.Printf("Targets removed by filters:")
+	for _, filter := range filters {
+		log.Printf("- %s:", filter)
+		for _, filtered := range filterToTargets[filter] {
+			log.Printf("  - %s (%s)", filtered.Target.Name, pathRelativeToWorkspace(filtered.ProjectPath, containerPath))
+		}
+	}
+}
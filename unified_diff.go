@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// unifiedDiff returns the unified diff of the given lines, or an empty string if they are equal.
+func unifiedDiff(fromName, toName string, from, to []string) string {
+	ops := diffLines(from, to)
+
+	changed := false
+	for _, op := range ops {
+		if op.kind != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n", fromName)
+	fmt.Fprintf(&b, "+++ %s\n", toName)
+
+	// fromLine and toLine are the (0 based) line numbers before each op.
+	fromLine := make([]int, len(ops)+1)
+	toLine := make([]int, len(ops)+1)
+	for i, op := range ops {
+		fromLine[i+1], toLine[i+1] = fromLine[i], toLine[i]
+		if op.kind != '+' {
+			fromLine[i+1]++
+		}
+		if op.kind != '-' {
+			toLine[i+1]++
+		}
+	}
+
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		// Extend the hunk until there are more unchanged lines than the context on both sides.
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*diffContextLines {
+				break
+			}
+		}
+
+		hunkStart := maxInt(0, start-diffContextLines)
+		hunkEnd := minInt(len(ops), end+diffContextLines)
+
+		fromCount := fromLine[hunkEnd] - fromLine[hunkStart]
+		toCount := toLine[hunkEnd] - toLine[hunkStart]
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(fromLine[hunkStart], fromCount), hunkRange(toLine[hunkStart], toCount))
+		for _, op := range ops[hunkStart:hunkEnd] {
+			fmt.Fprintf(&b, "%c%s\n", op.kind, op.line)
+		}
+
+		start = hunkEnd
+	}
+
+	return b.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// diffLines returns the edit script turning from into to, based on the longest common subsequence.
+func diffLines(from, to []string) []diffOp {
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = maxInt(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			ops = append(ops, diffOp{kind: ' ', line: from[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{kind: '-', line: from[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: to[j]})
+			j++
+		}
+	}
+	for ; i < len(from); i++ {
+		ops = append(ops, diffOp{kind: '-', line: from[i]})
+	}
+	for ; j < len(to); j++ {
+		ops = append(ops, diffOp{kind: '+', line: to[j]})
+	}
+
+	return ops
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// numberedLines returns the lines "1" to "n", with the given lines replaced, an empty replacement removes the line.
+func numberedLines(n int, replacements map[int]string) []string {
+	var lines []string
+	for i := 1; i <= n; i++ {
+		line, ok := replacements[i]
+		if !ok {
+			line = fmt.Sprint(i)
+		} else if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// The expected hunks are the output of `diff -u`.
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		from []string
+		to   []string
+		want string
+	}{
+		{
+			name: "equal",
+			from: numberedLines(3, nil),
+			to:   numberedLines(3, nil),
+			want: "",
+		},
+		{
+			name: "changes separated by the context merge into one hunk",
+			from: numberedLines(16, nil),
+			to:   numberedLines(16, map[int]string{2: "two", 9: "", 16: "sixteen"}),
+			want: `@@ -1,16 +1,15 @@
+ 1
+-2
++two
+ 3
+ 4
+ 5
+ 6
+ 7
+ 8
+-9
+ 10
+ 11
+ 12
+ 13
+ 14
+ 15
+-16
++sixteen
+`,
+		},
+		{
+			name: "changes further apart than twice the context split into hunks",
+			from: numberedLines(16, nil),
+			to:   numberedLines(16, map[int]string{1: "one", 9: "nine"}),
+			want: `@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -6,7 +6,7 @@
+ 6
+ 7
+ 8
+-9
++nine
+ 10
+ 11
+ 12
+`,
+		},
+		{
+			name: "added file",
+			from: nil,
+			to:   []string{"x", "y"},
+			want: `@@ -0,0 +1,2 @@
++x
++y
+`,
+		},
+		{
+			name: "removed file",
+			from: []string{"x", "y"},
+			to:   nil,
+			want: `@@ -1,2 +0,0 @@
+-x
+-y
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			if want != "" {
+				want = "--- a\n+++ b\n" + want
+			}
+			if got := unifiedDiff("a", "b", tt.from, tt.to); got != want {
+				t.Errorf("unifiedDiff() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestHunkRange(t *testing.T) {
+	for _, tt := range []struct {
+		start, count int
+		want         string
+	}{
+		{0, 0, "0,0"},
+		{4, 1, "5"},
+		{4, 3, "5,3"},
+	} {
+		if got := hunkRange(tt.start, tt.count); got != tt.want {
+			t.Errorf("hunkRange(%d, %d) = %s, want %s", tt.start, tt.count, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-io/go-utils/v2/env"
+	xcodeproject "github.com/bitrise-io/go-xcode/xcodeproject/xcodeproj"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+const (
+	outOfRootProjectsFail = "fail"
+	outOfRootProjectsSkip = "skip"
+
+	sourceDirEnvKey = "BITRISE_SOURCE_DIR"
+)
+
+// defaultWriteRoot returns the git repository root of the containers' directory, or the source directory, or the containers' directory.
+func defaultWriteRoot(containersDir string, envRepository env.Repository) (string, error) {
+	repositoryRoot, found, err := findRepositoryRoot(containersDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the repository root: %w", err)
+	}
+	if found {
+		return repositoryRoot, nil
+	}
+
+	if sourceDir := envRepository.Get(sourceDirEnvKey); sourceDir != "" {
+		return sourceDir, nil
+	}
+
+	return containersDir, nil
+}
+
+// resolvePath resolves the symlinks of the existing part of the absolute path, failing on a dangling symlink.
+func resolvePath(pth string) (string, error) {
+	pth, err := filepath.Abs(pth)
+	if err != nil {
+		return "", err
+	}
+
+	existing := pth
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		if info, err := os.Lstat(existing); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%s is a dangling symlink", existing)
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return pth, nil
+		}
+		missing = append([]string{filepath.Base(existing)}, missing...)
+		existing = parent
+	}
+}
+
+// isPathInDir returns true if the path is the directory or is inside of it.
+func isPathInDir(pth, dir string) bool {
+	rel, err := filepath.Rel(dir, pth)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func containsPath(paths []string, pth string) bool {
+	for _, p := range paths {
+		if p == pth {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWritePath returns an error if the resolved path is outside of the write root, an empty root allows any path.
+func checkWritePath(root, pth string) error {
+	if root == "" {
+		return nil
+	}
+
+	resolved, err := resolvePath(pth)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", pth, err)
+	}
+	if !isPathInDir(resolved, root) {
+		if resolved != pth {
+			return fmt.Errorf("%s (resolved to %s) is outside of the write root (%s)", pth, resolved, root)
+		}
+		return fmt.Errorf("%s is outside of the write root (%s)", pth, root)
+	}
+	return nil
+}
+
+// outOfRootProject is a project of the container outside of the write root, err tells why.
+type outOfRootProject struct {
+	path string
+	err  error
+}
+
+// outOfRootProjects returns the opened projects of the container outside of the write root.
+func outOfRootProjects(cfg Config, container schemelist.Container) []outOfRootProject {
+	projects, _, _ := container.Projects()
+
+	var outOfRoot []outOfRootProject
+	for _, project := range projects {
+		if err := checkWritePath(cfg.WriteRoot, project.Path); err != nil {
+			outOfRoot = append(outOfRoot, outOfRootProject{path: project.Path, err: err})
+		}
+	}
+	return outOfRoot
+}
+
+// projectsInWriteRoot returns the projects the step can write to, the others are reported by outOfRootProjects.
+func projectsInWriteRoot(cfg Config, projects []xcodeproject.XcodeProj) []xcodeproject.XcodeProj {
+	var inRoot []xcodeproject.XcodeProj
+	for _, project := range projects {
+		if checkWritePath(cfg.WriteRoot, project.Path) == nil {
+			inRoot = append(inRoot, project)
+		}
+	}
+	return inRoot
+}
+
+// confineWrites reports the projects outside of the write root, and returns the warnings of the skipped ones.
+func (g SchemeGenerator) confineWrites(cfg Config, container schemelist.Container) ([]string, error) {
+	projects := outOfRootProjects(cfg, container)
+	if len(projects) == 0 {
+		return nil, nil
+	}
+
+	fmt.Println()
+	log.Warnf("Projects outside of the write root (%s):", cfg.WriteRoot)
+	for _, project := range projects {
+		log.Warnf("- %s", project.err)
+	}
+
+	if cfg.OutOfRootProjects == outOfRootProjectsFail {
+		return nil, fmt.Errorf("%d project(s) are outside of the write root, the step does not write to them", len(projects))
+	}
+	log.Warnf("Skipping them, no Scheme is written to them.")
+
+	var warnings []string
+	for _, project := range projects {
+		warnings = append(warnings, fmt.Sprintf("project skipped: %s is outside of the write root", pathRelativeToWorkspace(project.path, cfg.ContainerPath)))
+	}
+	return warnings, nil
+}
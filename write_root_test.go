@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsPathInDir(t *testing.T) {
+	tests := []struct {
+		name string
+		pth  string
+		dir  string
+		want bool
+	}{
+		{name: "the directory itself", pth: "/root/a", dir: "/root/a", want: true},
+		{name: "inside", pth: "/root/a/App.xcodeproj", dir: "/root/a", want: true},
+		{name: "inside, with .. in a file name", pth: "/root/a/..App.xcodeproj", dir: "/root/a", want: true},
+		{name: "parent", pth: "/root", dir: "/root/a", want: false},
+		{name: "prefix sibling", pth: "/root/ab", dir: "/root/a", want: false},
+		{name: "inside a prefix sibling", pth: "/root/ab/App.xcodeproj", dir: "/root/a", want: false},
+		{name: ".. escape", pth: "/root/a/../b/App.xcodeproj", dir: "/root/a", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPathInDir(tt.pth, tt.dir); got != tt.want {
+				t.Errorf("isPathInDir(%s, %s) = %v, want %v", tt.pth, tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckWritePath(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, "a")
+	makeTestDirs(t, filepath.Join(root, "App.xcodeproj"), filepath.Join(dir, "ab", "App.xcodeproj"), filepath.Join(dir, "outside"))
+	if err := os.WriteFile(filepath.Join(dir, "outside", "App.xcscheme"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{
+		"Outside.xcodeproj": filepath.Join(dir, "outside"),
+		"Outside.xcscheme":  filepath.Join(dir, "outside", "App.xcscheme"),
+		"Inside.xcodeproj":  filepath.Join(root, "App.xcodeproj"),
+		"Dangling.xcscheme": filepath.Join(root, "Missing.xcscheme"),
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		root    string
+		pth     string
+		wantErr string
+	}{
+		{name: "no write root", pth: filepath.Join(dir, "outside", "App.xcscheme")},
+		{name: "the write root itself", root: root, pth: root},
+		{name: "existing path", root: root, pth: filepath.Join(root, "App.xcodeproj")},
+		{name: "path to create", root: root, pth: filepath.Join(root, "App.xcodeproj", "xcshareddata", "xcschemes", "App.xcscheme")},
+		{name: ".. escape", root: root, pth: filepath.Join(root, "App.xcodeproj") + "/../../outside/App.xcscheme", wantErr: "is outside of the write root"},
+		{name: "prefix sibling directory", root: root, pth: filepath.Join(dir, "ab", "App.xcodeproj"), wantErr: "is outside of the write root"},
+		{name: "symlinked directory inside the root", root: root, pth: filepath.Join(root, "Inside.xcodeproj", "xcshareddata")},
+		{name: "symlinked directory outside the root", root: root, pth: filepath.Join(root, "Outside.xcodeproj", "xcshareddata"), wantErr: "resolved to " + filepath.Join(dir, "outside", "xcshareddata")},
+		{name: "symlinked file outside the root", root: root, pth: filepath.Join(root, "Outside.xcscheme"), wantErr: "resolved to " + filepath.Join(dir, "outside", "App.xcscheme")},
+		{name: "dangling symlink", root: root, pth: filepath.Join(root, "Dangling.xcscheme"), wantErr: "is a dangling symlink"},
+		{name: "path under a dangling symlink", root: root, pth: filepath.Join(root, "Dangling.xcscheme", "App.xcscheme"), wantErr: "is a dangling symlink"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkError(t, checkWritePath(tt.root, tt.pth), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bitrise-io/go-utils/log"
+	"github.com/bitrise-steplib/steps-recreate-user-schemes/schemelist"
+)
+
+const xcodebuildListFileName = "xcodebuild_list.json"
+
+// list prints the `xcodebuild -list -json` equivalent of the container, and saves it to the deploy directory if set.
+func (g SchemeGenerator) list(cfg Config) (schemelist.List, error) {
+	list, err := schemelist.ListContainer(cfg.ContainerPath, cfg.Projects)
+	if err != nil {
+		return schemelist.List{}, fmt.Errorf("listing %s failed: %w", filepath.Base(cfg.ContainerPath), err)
+	}
+
+	contents, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return schemelist.List{}, fmt.Errorf("failed to marshal list: %w", err)
+	}
+	contents = append(contents, '\n')
+
+	fmt.Println()
+	fmt.Print(string(contents))
+
+	if len(cfg.ContainerPaths) > 1 {
+		return list, nil
+	}
+	return list, saveList(cfg, contents)
+}
+
+func saveList(cfg Config, contents []byte) error {
+	if cfg.DeployDir == "" {
+		return nil
+	}
+
+	pth := filepath.Join(cfg.DeployDir, xcodebuildListFileName)
+	if err := os.WriteFile(pth, contents, 0600); err != nil {
+		return fmt.Errorf("saving list failed: %w", err)
+	}
+
+	fmt.Println()
+	log.Printf("List saved to: %s", pth)
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const xmlIndent = "   "
+
+// xmlDocument keeps the nodes of an XML document in order, whitespace-only text is reformatted and CDATA is written as escaped text.
+type xmlDocument struct {
+	// Prolog holds the processing instructions, directives (for example <!DOCTYPE>) and comments before the root element.
+	Prolog []xml.Token
+	Root   *xmlElement
+}
+
+type xmlAttr struct {
+	Name  string
+	Value string
+}
+
+type xmlElement struct {
+	Name     string
+	Attrs    []xmlAttr
+	Children []xmlNode
+}
+
+// xmlNode is a child of an element: either an element, a text or a comment.
+type xmlNode struct {
+	Element *xmlElement
+	Text    string
+	Comment string
+}
+
+func parseXMLDocument(data []byte) (*xmlDocument, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var doc xmlDocument
+	var stack []*xmlElement
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			element := &xmlElement{Name: qualifiedName(t.Name)}
+			for _, attr := range t.Attr {
+				element.Attrs = append(element.Attrs, xmlAttr{Name: qualifiedName(attr.Name), Value: attr.Value})
+			}
+
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, xmlNode{Element: element})
+			} else if doc.Root == nil {
+				doc.Root = element
+			} else {
+				return nil, fmt.Errorf("multiple root elements: %s, %s", doc.Root.Name, element.Name)
+			}
+			stack = append(stack, element)
+		case xml.EndElement:
+			if len(stack) == 0 || stack[len(stack)-1].Name != qualifiedName(t.Name) {
+				return nil, fmt.Errorf("unexpected end element: %s", qualifiedName(t.Name))
+			}
+			stack[len(stack)-1].dropFormatting()
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, xmlNode{Text: string(t)})
+			}
+		case xml.Comment:
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, xmlNode{Comment: string(t)})
+			} else if doc.Root == nil {
+				doc.Prolog = append(doc.Prolog, t.Copy())
+			}
+		case xml.ProcInst:
+			if doc.Root == nil {
+				doc.Prolog = append(doc.Prolog, t.Copy())
+			}
+		case xml.Directive:
+			if doc.Root == nil {
+				doc.Prolog = append(doc.Prolog, t.Copy())
+			}
+		}
+	}
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unclosed element: %s", stack[len(stack)-1].Name)
+	}
+	if doc.Root == nil {
+		return nil, fmt.Errorf("no root element")
+	}
+
+	return &doc, nil
+}
+
+// Marshal writes the document in the layout Xcode uses for schemes.
+func (d xmlDocument) Marshal() ([]byte, error) {
+	if d.Root == nil {
+		return nil, fmt.Errorf("no root element")
+	}
+
+	var b bytes.Buffer
+	for _, token := range d.Prolog {
+		switch t := token.(type) {
+		case xml.ProcInst:
+			fmt.Fprintf(&b, "<?%s %s?>\n", t.Target, t.Inst)
+		case xml.Directive:
+			fmt.Fprintf(&b, "<!%s>\n", t)
+		case xml.Comment:
+			fmt.Fprintf(&b, "<!--%s-->\n", t)
+		}
+	}
+	d.Root.write(&b, "")
+	b.WriteString("\n")
+
+	return b.Bytes(), nil
+}
+
+func (e *xmlElement) write(b *bytes.Buffer, indent string) {
+	b.WriteString(indent + "<" + e.Name)
+	for _, attr := range e.Attrs {
+		fmt.Fprintf(b, "\n%s%s%s = \"%s\"", indent, xmlIndent, attr.Name, escapeXMLAttribute(attr.Value))
+	}
+	b.WriteString(">")
+
+	// The children of an element with text are written as they are, indenting them would change the text.
+	if e.hasText() {
+		for _, child := range e.Children {
+			switch {
+			case child.Element != nil:
+				child.Element.write(b, "")
+			case child.Comment != "":
+				b.WriteString("<!--" + child.Comment + "-->")
+			default:
+				b.WriteString(escapeXMLText(child.Text))
+			}
+		}
+		b.WriteString("</" + e.Name + ">")
+		return
+	}
+
+	for _, child := range e.Children {
+		b.WriteString("\n")
+		if child.Element != nil {
+			child.Element.write(b, indent+xmlIndent)
+		} else {
+			b.WriteString(indent + xmlIndent + "<!--" + child.Comment + "-->")
+		}
+	}
+	b.WriteString("\n" + indent + "</" + e.Name + ">")
+}
+
+func (e *xmlElement) hasText() bool {
+	for _, child := range e.Children {
+		if child.Element == nil && child.Comment == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// dropFormatting removes the whitespace-only texts, if the element has no other text.
+func (e *xmlElement) dropFormatting() {
+	for _, child := range e.Children {
+		if child.Element == nil && child.Comment == "" && strings.TrimSpace(child.Text) != "" {
+			return
+		}
+	}
+
+	var children []xmlNode
+	for _, child := range e.Children {
+		if child.Element != nil || child.Comment != "" {
+			children = append(children, child)
+		}
+	}
+	e.Children = children
+}
+
+func (e *xmlElement) attr(name string) (string, bool) {
+	for _, attr := range e.Attrs {
+		if attr.Name == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// setAttr updates the value of the attribute in place, or appends the attribute if the element does not have it yet.
+func (e *xmlElement) setAttr(name, value string) {
+	for i, attr := range e.Attrs {
+		if attr.Name == name {
+			e.Attrs[i].Value = value
+			return
+		}
+	}
+	e.Attrs = append(e.Attrs, xmlAttr{Name: name, Value: value})
+}
+
+// walk calls fn for the element and all of its descendants, in document order.
+func (e *xmlElement) walk(fn func(*xmlElement)) {
+	fn(e)
+	for _, child := range e.Children {
+		if child.Element != nil {
+			child.Element.walk(fn)
+		}
+	}
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// The escapers keep apostrophes, as attribute values are always double-quoted.
+var (
+	xmlAttributeEscaper = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		`"`, "&quot;",
+		"\n", "&#10;",
+		"\r", "&#13;",
+		"\t", "&#9;",
+	)
+	xmlTextEscaper = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+	)
+)
+
+func escapeXMLAttribute(value string) string {
+	return xmlAttributeEscaper.Replace(value)
+}
+
+func escapeXMLText(text string) string {
+	return xmlTextEscaper.Replace(text)
+}
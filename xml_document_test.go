@@ -0,0 +1,151 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The schemes written by Xcode are written back byte for byte.
+func TestXMLDocumentRoundTrip(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.xcscheme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no schemes in testdata")
+	}
+
+	for _, pth := range paths {
+		t.Run(filepath.Base(pth), func(t *testing.T) {
+			content, err := os.ReadFile(pth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, err := parseXMLDocument(content)
+			if err != nil {
+				t.Fatalf("parseXMLDocument() error = %v", err)
+			}
+			got, err := doc.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if diff := unifiedDiff(pth, "Marshal()", strings.Split(string(content), "\n"), strings.Split(string(got), "\n")); diff != "" {
+				t.Errorf("round trip changed the document:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestXMLDocumentKeepsUnknownNodes(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<!-- generated -->
+<Scheme
+   version = "1.7">
+   <!-- kept -->
+   <CustomAction
+      value = "a &quot;quoted&quot;&#10;line's end">
+      <Note>x &lt; y</Note>
+   </CustomAction>
+</Scheme>
+`
+	doc, err := parseXMLDocument([]byte(content))
+	if err != nil {
+		t.Fatalf("parseXMLDocument() error = %v", err)
+	}
+
+	var custom *xmlElement
+	doc.Root.walk(func(e *xmlElement) {
+		if e.Name == "CustomAction" {
+			custom = e
+		}
+	})
+	if custom == nil {
+		t.Fatal("CustomAction is not parsed")
+	}
+	if value, _ := custom.attr("value"); value != "a \"quoted\"\nline's end" {
+		t.Errorf("value = %q", value)
+	}
+
+	doc.Root.setAttr("version", "2.0")
+	doc.Root.setAttr("LastUpgradeVersion", "1500")
+	got, err := doc.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	want := strings.Replace(content, `   version = "1.7">`, `   version = "2.0"
+   LastUpgradeVersion = "1500">`, 1)
+	if string(got) != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseXMLDocumentErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "no root", content: `<?xml version="1.0"?>`, wantErr: "no root element"},
+		{name: "multiple roots", content: `<A></A><B></B>`, wantErr: "multiple root elements: A, B"},
+		{name: "unclosed", content: `<A><B></B>`, wantErr: "unclosed element: A"},
+		{name: "mismatched end", content: `<A><B></A>`, wantErr: "unexpected end element: A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseXMLDocument([]byte(tt.content))
+			checkError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestXMLDocumentText(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "whitespace-only text is formatting",
+			content: "<A>\n  <B>   </B>\n\t<C/>\n</A>",
+			want:    "<A>\n   <B>\n   </B>\n   <C>\n   </C>\n</A>\n",
+		},
+		{
+			name:    "surrounding whitespace of a text",
+			content: "<A>\n   <B>  x\n  </B>\n</A>",
+			want:    "<A>\n   <B>  x\n  </B>\n</A>\n",
+		},
+		{
+			name:    "mixed text",
+			content: "<A> x <B>y</B> <C/> <!--c-->z </A>",
+			want:    "<A> x <B>y</B> <C>\n</C> <!--c-->z </A>\n",
+		},
+		{
+			name:    "CDATA section",
+			content: "<A><![CDATA[a < b && c]]></A>",
+			want:    "<A>a &lt; b &amp;&amp; c</A>\n",
+		},
+		{
+			name:    "doctype",
+			content: "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE A SYSTEM \"a.dtd\">\n<A>\n</A>\n",
+			want:    "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<!DOCTYPE A SYSTEM \"a.dtd\">\n<A>\n</A>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseXMLDocument([]byte(tt.content))
+			if err != nil {
+				t.Fatalf("parseXMLDocument() error = %v", err)
+			}
+			got, err := doc.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}